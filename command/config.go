@@ -0,0 +1,134 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/util"
+)
+
+var configCommand = &cobra.Command{
+	Use:   "config",
+	Short: "Manage configuration",
+}
+
+var configShowCommand = &cobra.Command{
+	Use:          "show",
+	Short:        "Show configuration",
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		resolved, _ := command.Flags().GetBool(util.HandyCiFlagResolved)
+
+		if resolved {
+			encoder := yaml.NewEncoder(command.OutOrStdout())
+			encoder.SetIndent(2)
+
+			return encoder.Encode(config.HandyCiConfig)
+		}
+
+		if viper.ConfigFileUsed() == "" {
+			return fmt.Errorf("no config file found")
+		}
+
+		content, err := os.ReadFile(viper.ConfigFileUsed())
+		if err != nil {
+			return err
+		}
+
+		_, err = command.OutOrStdout().Write(content)
+
+		return err
+	},
+}
+
+var configMigrateCommand = &cobra.Command{
+	Use:          "migrate",
+	Short:        "Migrate configuration to the current schema version",
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		configFile := viper.ConfigFileUsed()
+		if configFile == "" {
+			return fmt.Errorf("no config file found")
+		}
+
+		document, err := config.ReadDocument(configFile)
+		if err != nil {
+			return err
+		}
+
+		migrations, err := config.Migrate(document)
+		if err != nil {
+			return err
+		}
+
+		if len(migrations) == 0 {
+			util.Printf("Config version is current, nothing to migrate\n")
+			return nil
+		}
+
+		for _, migration := range migrations {
+			util.Printf("MIGRATION: %d -> %d %s\n", migration.From, migration.To, migration.Description)
+		}
+
+		content, err := config.EncodeDocument(document)
+		if err != nil {
+			return err
+		}
+
+		dryRun, _ := command.Flags().GetBool(util.HandyCiFlagDryRun)
+
+		if dryRun {
+			_, err = command.OutOrStdout().Write(content)
+			return err
+		}
+
+		original, err := os.ReadFile(configFile)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(configFile+".bak", original, 0644)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(configFile, content, 0644)
+		if err != nil {
+			return err
+		}
+
+		util.Printf("Migrated %s, original saved as %s\n", configFile, configFile+".bak")
+
+		return nil
+	},
+}
+
+var configSchemaCommand = &cobra.Command{
+	Use:          "schema",
+	Short:        "Print JSON Schema of configuration, for validation and completion in editors",
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		encoder := json.NewEncoder(command.OutOrStdout())
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(config.Schema())
+	},
+}
+
+func init() {
+	rootCommand.AddCommand(configCommand)
+	configCommand.AddCommand(configShowCommand)
+	configCommand.AddCommand(configMigrateCommand)
+	configCommand.AddCommand(configSchemaCommand)
+
+	configShowCommand.Flags().SortFlags = false
+
+	configShowCommand.Flags().Bool(
+		util.HandyCiFlagResolved, false, "Show repositories after template expansion and inheritance")
+}
@@ -0,0 +1,244 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/execution"
+	"github.com/carrchang/handy-ci/util"
+)
+
+var configAddWorkspaceCommand = &cobra.Command{
+	Use:          "add-workspace NAME",
+	Short:        "Add a workspace",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		path, _ := command.Flags().GetString(util.HandyCiFlagPath)
+		if path == "" {
+			return fmt.Errorf("--%s is required", util.HandyCiFlagPath)
+		}
+
+		_, err := editConfig(func(document *yaml.Node) error {
+			return config.AddWorkspace(document, config.Workspace{Name: args[0], Path: path})
+		})
+
+		return err
+	},
+}
+
+var configAddGroupCommand = &cobra.Command{
+	Use:          "add-group NAME",
+	Short:        "Add a group to a workspace",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		workspace, _ := command.Flags().GetString(util.HandyCiFlagWorkspace)
+		path, _ := command.Flags().GetString(util.HandyCiFlagPath)
+		nameIgnoredInPath, _ := command.Flags().GetBool(util.HandyCiFlagNameIgnoredInPath)
+
+		_, err := editConfig(func(document *yaml.Node) error {
+			return config.AddGroup(document, workspace, config.Group{
+				Name:              args[0],
+				Path:              path,
+				NameIgnoredInPath: nameIgnoredInPath,
+			})
+		})
+
+		return err
+	},
+}
+
+var configAddRepoCommand = &cobra.Command{
+	Use:          "add-repo [NAME]",
+	Short:        "Add a repository to a group",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		workspaceName, _ := command.Flags().GetString(util.HandyCiFlagWorkspace)
+		groupName, _ := command.Flags().GetString(util.HandyCiFlagGroup)
+		path, _ := command.Flags().GetString(util.HandyCiFlagPath)
+		remotes, _ := command.Flags().GetStringArray(util.HandyCiFlagRemote)
+		fromURL, _ := command.Flags().GetString(util.HandyCiFlagFromURL)
+		noClone, _ := command.Flags().GetBool(util.HandyCiFlagNoClone)
+
+		repository := config.Repository{Path: path}
+
+		if len(args) > 0 {
+			repository.Name = args[0]
+		}
+
+		if fromURL != "" {
+			if repository.Name == "" {
+				repository.Name = config.RepositoryNameFromURL(fromURL)
+			}
+
+			repository.Remotes = append(repository.Remotes, config.GitRemote{Name: "origin", URL: fromURL})
+		}
+
+		if repository.Name == "" {
+			return fmt.Errorf("repository name or --%s is required", util.HandyCiFlagFromURL)
+		}
+
+		for _, remote := range remotes {
+			name, url, found := strings.Cut(remote, "=")
+			if !found {
+				return fmt.Errorf("remote %s should be in NAME=URL format", remote)
+			}
+
+			repository.Remotes = append(repository.Remotes, config.GitRemote{Name: name, URL: url})
+		}
+
+		loaded, err := editConfig(func(document *yaml.Node) error {
+			return config.AddRepository(document, workspaceName, groupName, repository)
+		})
+		if err != nil {
+			return err
+		}
+
+		if fromURL == "" || noClone {
+			return nil
+		}
+
+		for _, workspace := range loaded.Workspaces {
+			for _, group := range workspace.Groups {
+				if (workspaceName == "" || workspace.Name == workspaceName) && (groupName == "" || group.Name == groupName) {
+					for _, added := range group.Repositories {
+						if added.Name == repository.Name {
+							return execution.Clone(workspace, group, added)
+						}
+					}
+				}
+			}
+		}
+
+		return nil
+	},
+}
+
+var configRemoveRepoCommand = &cobra.Command{
+	Use:          "remove-repo NAME",
+	Short:        "Remove a repository",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		workspace, _ := command.Flags().GetString(util.HandyCiFlagWorkspace)
+		group, _ := command.Flags().GetString(util.HandyCiFlagGroup)
+
+		_, err := editConfig(func(document *yaml.Node) error {
+			return config.RemoveRepository(document, workspace, group, args[0])
+		})
+
+		return err
+	},
+}
+
+var configSetCommand = &cobra.Command{
+	Use:          "set KEY VALUE",
+	Short:        "Set a value by dot-delimited key, e.g. workspaces.keepnative.path",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		_, err := editConfig(func(document *yaml.Node) error {
+			return config.Set(document, args[0], args[1])
+		})
+
+		return err
+	},
+}
+
+// configFile returns the config file in use, or the default config file when there is none yet.
+func configFile() string {
+	if viper.ConfigFileUsed() != "" {
+		return viper.ConfigFileUsed()
+	}
+
+	return filepath.Join(util.Home(), "."+util.HandyCiName, util.HandyCiFlagConfig+".yaml")
+}
+
+// editConfig applies edit to the config file, keeping comments and formatting. The result is validated
+// the same way as it is loaded, and written only when valid.
+func editConfig(edit func(document *yaml.Node) error) (*config.Config, error) {
+	file := configFile()
+
+	document, err := config.ReadDocument(file)
+	if os.IsNotExist(err) {
+		document, err = config.NewDocument(), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := config.DocumentVersion(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if version < config.CurrentVersion {
+		return nil, fmt.Errorf("config version %d is outdated, run \"handy-ci config migrate\" first", version)
+	}
+
+	err = edit(document)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := config.EncodeDocument(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var edited yaml.Node
+
+	err = yaml.Unmarshal(content, &edited)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded, err := config.LoadDocument(&edited)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(file, content, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	util.Printf("Updated %s\n", file)
+
+	return loaded, nil
+}
+
+func init() {
+	configCommand.AddCommand(configAddWorkspaceCommand)
+	configCommand.AddCommand(configAddGroupCommand)
+	configCommand.AddCommand(configAddRepoCommand)
+	configCommand.AddCommand(configRemoveRepoCommand)
+	configCommand.AddCommand(configSetCommand)
+
+	configAddWorkspaceCommand.Flags().String(util.HandyCiFlagPath, "", "Path of workspace")
+
+	configAddGroupCommand.Flags().String(util.HandyCiFlagPath, "", "Path of group, relative to workspace or absolute")
+	configAddGroupCommand.Flags().Bool(util.HandyCiFlagNameIgnoredInPath, false, "Don't append group name to path")
+
+	configAddRepoCommand.Flags().SortFlags = false
+	configAddRepoCommand.Flags().String(util.HandyCiFlagPath, "", "Path of repository, relative to group or absolute")
+	configAddRepoCommand.Flags().StringArray(util.HandyCiFlagRemote, nil, "Git remote in NAME=URL format, can be repeated")
+	configAddRepoCommand.Flags().String(
+		util.HandyCiFlagFromURL, "", "Infer name and origin remote from git URL, and clone the repository")
+	configAddRepoCommand.Flags().Bool(util.HandyCiFlagNoClone, false, "Don't clone the repository added from URL")
+}
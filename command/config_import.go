@@ -0,0 +1,80 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/util"
+)
+
+var configImportCommand = &cobra.Command{
+	Use:          "import FILE",
+	Short:        "Import a workspace from configuration of other multi-repository tools",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		format, _ := command.Flags().GetString(util.HandyCiFlagFormat)
+		name, _ := command.Flags().GetString(util.HandyCiFlagName)
+		write, _ := command.Flags().GetBool(util.HandyCiFlagWrite)
+
+		if format == "" {
+			return fmt.Errorf("--%s is required, one of %s", util.HandyCiFlagFormat, strings.Join(config.ImportFormats, ", "))
+		}
+
+		file, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		workspace, problems, err := config.Import(format, content, filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+
+		if name != "" {
+			workspace.Name = name
+		}
+
+		if home := filepath.ToSlash(util.Home()); strings.HasPrefix(workspace.Path+"/", home+"/") {
+			workspace.Path = "$HOME" + strings.TrimPrefix(workspace.Path, home)
+		}
+
+		for _, problem := range problems {
+			util.Eprintln(problem)
+		}
+
+		if write {
+			_, err = editConfig(func(document *yaml.Node) error {
+				return config.AddWorkspace(document, workspace)
+			})
+
+			return err
+		}
+
+		encoder := yaml.NewEncoder(command.OutOrStdout())
+		encoder.SetIndent(2)
+
+		return encoder.Encode(config.Config{Version: config.CurrentVersion, Workspaces: []config.Workspace{workspace}})
+	},
+}
+
+func init() {
+	configCommand.AddCommand(configImportCommand)
+
+	configImportCommand.Flags().SortFlags = false
+	configImportCommand.Flags().String(
+		util.HandyCiFlagFormat, "", "Format of FILE, one of "+strings.Join(config.ImportFormats, ", "))
+	configImportCommand.Flags().String(util.HandyCiFlagName, "", "Name of workspace, default is directory name of FILE")
+	configImportCommand.Flags().Bool(util.HandyCiFlagWrite, false, "Add workspace to config file instead of printing it")
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/util"
+)
+
+func TestConfigShowCommand_Resolved(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+
+	config.HandyCiConfig = &config.Config{
+		RepositoryTemplates: []config.Repository{{Name: "java", Tags: []string{"java"}}},
+		Workspaces: []config.Workspace{{Name: "ws", Groups: []config.Group{{Name: "g", Repositories: []config.Repository{
+			{Name: "orders", Extends: []string{"java"}},
+		}}}}},
+	}
+	if err := config.HandyCiConfig.Resolve(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	configShowCommand.SetOut(buf)
+	configShowCommand.Flags().Set(util.HandyCiFlagResolved, "true")
+	defer configShowCommand.Flags().Set(util.HandyCiFlagResolved, "false")
+
+	if err := configShowCommand.RunE(configShowCommand, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "name: orders") || !strings.Contains(buf.String(), "- java") {
+		t.Fatalf("expected resolved repository in output, got: %s", buf.String())
+	}
+}
+
+func TestConfigSchemaCommand(t *testing.T) {
+	buf := &bytes.Buffer{}
+	configSchemaCommand.SetOut(buf)
+
+	if err := configSchemaCommand.RunE(configSchemaCommand, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("expected JSON schema, got %v: %s", err, buf.String())
+	}
+	if _, found := schema["definitions"].(map[string]interface{})["Workspace"]; !found {
+		t.Fatalf("expected Workspace definition in schema: %s", buf.String())
+	}
+}
+
+func TestConfigImportCommand_CurrentVersion(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".meta")
+	meta := `{"projects": {"api": "git@github.com:x/api.git"}}`
+	if err := os.WriteFile(file, []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	configImportCommand.SetOut(buf)
+	configImportCommand.Flags().Set(util.HandyCiFlagFormat, config.ImportFormatMeta)
+	defer configImportCommand.Flags().Set(util.HandyCiFlagFormat, "")
+
+	if err := configImportCommand.RunE(configImportCommand, []string{file}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version, err := config.DocumentVersion(&document); err != nil || version != config.CurrentVersion {
+		t.Fatalf("expected current version in imported config, got %d, %v: %s", version, err, buf.String())
+	}
+	if _, err := config.LoadDocument(&document); err != nil {
+		t.Fatalf("expected imported config to load, got %v: %s", err, buf.String())
+	}
+}
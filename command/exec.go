@@ -11,8 +11,10 @@ var execCommand = &cobra.Command{
   Use:                "exec",
   Short:              "Execute any command",
   DisableFlagParsing: true,
-  Run: func(command *cobra.Command, args []string) {
-    execution.Execute(command, args, execution.ExecExecution{})
+  SilenceUsage:       true,
+  SilenceErrors:      true,
+  RunE: func(command *cobra.Command, args []string) error {
+    return execution.Execute(command, args, execution.ExecExecution{})
   },
 }
 
@@ -0,0 +1,311 @@
+package command
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/carrchang/handy-ci/execution"
+	"github.com/carrchang/handy-ci/util"
+)
+
+const exportFormatVSCode = "vscode"
+const exportFormatIntelliJ = "intellij"
+const exportFormatRepo = "repo"
+const exportFormatMake = "make"
+
+var exportFormats = []string{exportFormatVSCode, exportFormatIntelliJ, exportFormatRepo, exportFormatMake}
+
+var exportCommand = &cobra.Command{
+	Use:          "export",
+	Short:        "Export selected repositories to IDE and CI formats",
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		format, _ := command.Flags().GetString(util.HandyCiFlagFormat)
+
+		targets := execution.Selection(command)
+
+		switch format {
+		case exportFormatVSCode:
+			return exportVSCode(command.OutOrStdout(), targets)
+		case exportFormatIntelliJ:
+			return exportIntelliJ(command.OutOrStdout(), targets)
+		case exportFormatRepo:
+			return exportRepoManifest(command.OutOrStdout(), targets)
+		case exportFormatMake:
+			return exportMakefile(command.OutOrStdout(), targets)
+		}
+
+		return fmt.Errorf("--%s is required, one of %s", util.HandyCiFlagFormat, strings.Join(exportFormats, ", "))
+	},
+}
+
+// exportVSCode writes a .code-workspace with a folder per repository.
+func exportVSCode(out io.Writer, targets []execution.Target) error {
+	type folder struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	}
+
+	codeWorkspace := struct {
+		Folders  []folder               `json:"folders"`
+		Settings map[string]interface{} `json:"settings"`
+	}{Folders: []folder{}, Settings: map[string]interface{}{}}
+
+	for _, target := range targets {
+		codeWorkspace.Folders = append(codeWorkspace.Folders, folder{
+			Name: target.Repository.Name,
+			Path: filepath.ToSlash(execution.RepositoryPath(target.Workspace, target.Group, target.Repository)),
+		})
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(codeWorkspace)
+}
+
+// exportIntelliJ writes the modules.xml of an IntelliJ project, with a module per repository grouped by
+// workspace and group.
+func exportIntelliJ(out io.Writer, targets []execution.Target) error {
+	type module struct {
+		FileURL  string `xml:"fileurl,attr"`
+		FilePath string `xml:"filepath,attr"`
+		Group    string `xml:"group,attr"`
+	}
+
+	project := struct {
+		XMLName   xml.Name `xml:"project"`
+		Version   string   `xml:"version,attr"`
+		Component struct {
+			Name    string   `xml:"name,attr"`
+			Modules []module `xml:"modules>module"`
+		} `xml:"component"`
+	}{Version: "4"}
+
+	project.Component.Name = "ProjectModuleManager"
+
+	for _, target := range targets {
+		path := filepath.ToSlash(execution.RepositoryPath(target.Workspace, target.Group, target.Repository))
+		moduleFile := path + "/" + target.Repository.Name + ".iml"
+
+		project.Component.Modules = append(project.Component.Modules, module{
+			FileURL:  "file://" + moduleFile,
+			FilePath: moduleFile,
+			Group:    target.Workspace.Name + "/" + target.Group.Name,
+		})
+	}
+
+	return writeXML(out, project)
+}
+
+// exportRepoManifest writes a repo manifest with a project per repository with origin remote. Remotes of
+// the manifest are the distinct bases of origin URLs, project paths are relative to the common directory
+// of the repositories.
+func exportRepoManifest(out io.Writer, targets []execution.Target) error {
+	type remote struct {
+		Name  string `xml:"name,attr"`
+		Fetch string `xml:"fetch,attr"`
+	}
+
+	type project struct {
+		Name   string `xml:"name,attr"`
+		Path   string `xml:"path,attr"`
+		Remote string `xml:"remote,attr"`
+		Groups string `xml:"groups,attr,omitempty"`
+	}
+
+	manifest := struct {
+		XMLName  xml.Name  `xml:"manifest"`
+		Remotes  []remote  `xml:"remote"`
+		Projects []project `xml:"project"`
+	}{}
+
+	var paths []string
+	for _, target := range targets {
+		paths = append(paths, filepath.ToSlash(execution.RepositoryPath(target.Workspace, target.Group, target.Repository)))
+	}
+
+	root := commonDirectory(paths)
+
+	for i, target := range targets {
+		url := execution.RepositoryRemoteURL(target.Repository, "origin")
+		if url == "" {
+			util.Eprintf("Repository %s has no origin remote, not exported\n", target.Repository.Name)
+			continue
+		}
+
+		index := strings.LastIndexAny(strings.TrimSuffix(url, "/"), "/:")
+		fetch, name := url[:index], url[index+1:]
+
+		var remoteName string
+		for _, existing := range manifest.Remotes {
+			if existing.Fetch == fetch {
+				remoteName = existing.Name
+			}
+		}
+
+		if remoteName == "" {
+			remoteName = "origin"
+			if len(manifest.Remotes) > 0 {
+				remoteName = fmt.Sprintf("origin%d", len(manifest.Remotes)+1)
+			}
+
+			manifest.Remotes = append(manifest.Remotes, remote{Name: remoteName, Fetch: fetch})
+		}
+
+		manifest.Projects = append(manifest.Projects, project{
+			Name:   name,
+			Path:   strings.TrimPrefix(strings.TrimPrefix(paths[i], root), "/"),
+			Remote: remoteName,
+			Groups: strings.Join(target.Repository.Tags, ","),
+		})
+	}
+
+	return writeXML(out, manifest)
+}
+
+// exportMakefile writes a Makefile with a target per repository running its default script, named by
+// makeTarget. The prerequisites of a target are the repositories it depends on and all runs every target
+// in dependency order.
+func exportMakefile(out io.Writer, targets []execution.Target) error {
+	targets, err := execution.OrderByDependencies(targets)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	repositories := map[string]string{}
+
+	for _, target := range targets {
+		name := makeTarget(target)
+		repository := target.Workspace.Name + "/" + target.Group.Name + "/" + target.Repository.Name
+
+		if other, found := repositories[name]; found {
+			return fmt.Errorf("repositories %s and %s have the same Makefile target %s", other, repository, name)
+		}
+
+		repositories[name] = repository
+		names = append(names, name)
+	}
+
+	fmt.Fprintf(out, "# Generated by %s export --format %s\n\n", util.HandyCiName, exportFormatMake)
+	fmt.Fprintf(out, ".PHONY: all %s\n\n", strings.Join(names, " "))
+	fmt.Fprintf(out, "all: %s\n", strings.Join(names, " "))
+
+	execCommand := &cobra.Command{Use: "exec"}
+
+	for i, target := range targets {
+		var prerequisites []string
+
+		// Dependencies are repositories of the same workspace, like when executing.
+		for _, dependency := range target.Repository.DependsOn {
+			for j, other := range targets {
+				if other.Workspace.Name == target.Workspace.Name && other.Repository.Name == dependency {
+					prerequisites = append(prerequisites, names[j])
+				}
+			}
+		}
+
+		fmt.Fprintf(out, "\n%s:", names[i])
+		if len(prerequisites) > 0 {
+			fmt.Fprintf(out, " %s", strings.Join(prerequisites, " "))
+		}
+		fmt.Fprintln(out)
+
+		executions, err := execution.ExecExecution{}.Parse(
+			execCommand, nil, target.Workspace, target.Group, target.Repository)
+		if err != nil {
+			return err
+		}
+
+		for _, scriptExecution := range executions {
+			var recipe string
+
+			if scriptExecution.AllowFailure {
+				recipe = "-"
+			}
+
+			recipe += "cd " + shellQuote(scriptExecution.Path) + " &&"
+
+			// Only values are quoted, a quoted assignment would be taken for the command by sh.
+			for _, variable := range scriptExecution.Env {
+				key, value, _ := strings.Cut(variable, "=")
+				recipe += " " + key + "=" + shellQuote(value)
+			}
+
+			for _, arg := range append([]string{scriptExecution.Command}, scriptExecution.Args...) {
+				recipe += " " + shellQuote(arg)
+			}
+
+			fmt.Fprintf(out, "\t%s\n", strings.ReplaceAll(recipe, "$", "$$"))
+		}
+	}
+
+	return nil
+}
+
+var makeUnsafe = regexp.MustCompile(`[^A-Za-z0-9_./+-]+`)
+
+// makeTarget returns the Makefile target of the repository of target, its group and name with characters
+// make treats specially replaced.
+func makeTarget(target execution.Target) string {
+	return makeUnsafe.ReplaceAllString(target.Group.Name+"/"+target.Repository.Name, "-")
+}
+
+func writeXML(out io.Writer, value interface{}) error {
+	fmt.Fprint(out, xml.Header)
+
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+
+	err := encoder.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+
+	return nil
+}
+
+// commonDirectory returns the deepest directory containing all paths.
+func commonDirectory(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	root := filepath.ToSlash(filepath.Dir(paths[0]))
+
+	for _, path := range paths {
+		for root != "/" && root != "." && !strings.HasPrefix(path+"/", strings.TrimSuffix(root, "/")+"/") {
+			root = filepath.ToSlash(filepath.Dir(root))
+		}
+	}
+
+	return root
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./:=@%+,-]+$`)
+
+// shellQuote quotes value for sh when needed.
+func shellQuote(value string) string {
+	if shellSafe.MatchString(value) {
+		return value
+	}
+
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func init() {
+	rootCommand.AddCommand(exportCommand)
+
+	exportCommand.Flags().String(
+		util.HandyCiFlagFormat, "", "Export format, one of "+strings.Join(exportFormats, ", "))
+}
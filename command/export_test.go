@@ -0,0 +1,118 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/execution"
+	"github.com/carrchang/handy-ci/util"
+)
+
+func TestExportCommand(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+
+	config.HandyCiConfig = &config.Config{Workspaces: []config.Workspace{{Name: "keepnative", Path: "/coding/keepnative", Groups: []config.Group{
+		{Name: "next", Repositories: []config.Repository{
+			{
+				Name:      "orders",
+				DependsOn: []string{"java"},
+				Scripts:   []config.Script{{Name: "mvn"}},
+				Remotes:   []config.GitRemote{{Name: "origin", URL: "git@gitlab.com:keepnative/orders.git"}},
+			},
+			{
+				Name:    "java",
+				Scripts: []config.Script{{Name: "mvn"}},
+				Remotes: []config.GitRemote{{Name: "origin", URL: "git@gitlab.com:keepnative/java.git"}},
+			},
+		}},
+	}}}}
+
+	export := func(format string) string {
+		buf := &bytes.Buffer{}
+		exportCommand.SetOut(buf)
+		exportCommand.Flags().Set(util.HandyCiFlagFormat, format)
+		defer exportCommand.Flags().Set(util.HandyCiFlagFormat, "")
+
+		if err := exportCommand.RunE(exportCommand, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return buf.String()
+	}
+
+	expectations := map[string][]string{
+		exportFormatVSCode:   {`"path": "/coding/keepnative/next/orders"`, `"name": "java"`},
+		exportFormatIntelliJ: {`filepath="/coding/keepnative/next/java/java.iml" group="keepnative/next"`},
+		exportFormatRepo:     {`<remote name="origin" fetch="git@gitlab.com:keepnative">`, `<project name="orders.git" path="orders" remote="origin">`},
+		exportFormatMake:     {"all: next/java next/orders\n", "\nnext/orders: next/java\n\tcd /coding/keepnative/next/orders && ", " mvn\n"},
+	}
+
+	for format, expected := range expectations {
+		output := export(format)
+
+		for _, text := range expected {
+			if !strings.Contains(output, text) {
+				t.Fatalf("expected %q in %s export:\n%s", text, format, output)
+			}
+		}
+	}
+
+	if err := exportCommand.RunE(exportCommand, nil); err == nil {
+		t.Fatalf("expected error without format")
+	}
+}
+
+func TestExportMakefile_TargetsByGroup(t *testing.T) {
+	workspace := config.Workspace{Name: "ws", Path: "/coding/ws"}
+	targets := []execution.Target{
+		{Workspace: workspace, Group: config.Group{Name: "next"}, Repository: config.Repository{Name: "api"}},
+		{Workspace: workspace, Group: config.Group{Name: "legacy apps"}, Repository: config.Repository{Name: "api"}},
+		{Workspace: workspace, Group: config.Group{Name: "next"}, Repository: config.Repository{Name: "web", DependsOn: []string{"api"}}},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := exportMakefile(buf, targets); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, text := range []string{"all: next/api legacy-apps/api next/web\n", "\nnext/web: next/api legacy-apps/api\n"} {
+		if !strings.Contains(buf.String(), text) {
+			t.Fatalf("expected %q in Makefile:\n%s", text, buf.String())
+		}
+	}
+
+	other := config.Workspace{Name: "other", Path: "/coding/other"}
+	targets = append(targets, execution.Target{Workspace: other, Group: config.Group{Name: "next"}, Repository: config.Repository{Name: "api"}})
+
+	if err := exportMakefile(&bytes.Buffer{}, targets); err == nil {
+		t.Fatalf("expected error for repositories with the same target")
+	}
+}
+
+func TestExportMakefile_QuotesEnvValues(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+
+	config.HandyCiConfig = &config.Config{}
+
+	targets := []execution.Target{{
+		Workspace: config.Workspace{Name: "ws", Path: "/coding/ws"},
+		Group:     config.Group{Name: "next"},
+		Repository: config.Repository{
+			Name:    "api",
+			Scripts: []config.Script{{Name: "printenv", Default: true, Env: map[string]string{"MAVEN_OPTS": "-Xmx1g -Dfoo=bar"}}},
+		},
+	}}
+
+	buf := &bytes.Buffer{}
+	if err := exportMakefile(buf, targets); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if text := " MAVEN_OPTS='-Xmx1g -Dfoo=bar' printenv\n"; !strings.Contains(buf.String(), text) {
+		t.Fatalf("expected %q in Makefile:\n%s", text, buf.String())
+	}
+}
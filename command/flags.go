@@ -0,0 +1,124 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/util"
+)
+
+const flagSourceCommandLine = "command line"
+const flagSourceEnv = "env"
+const flagSourceConfig = "config"
+const flagSourceDefault = "default"
+
+// flagSources records where the effective value of each root flag comes from.
+var flagSources = map[string]string{}
+
+// flagEnv returns the environment variable holding the default of flag, such as HANDY_CI_OPT_DRY_RUN. The
+// prefix keeps them apart from the variables executions set, such as HANDY_CI_WORKSPACE.
+func flagEnv(flag string) string {
+	return "HANDY_CI_OPT_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// defaultedFlags returns the root flags which take defaults from env and config.
+func defaultedFlags() []string {
+	var names []string
+
+	rootCommand.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Name != util.HandyCiFlagConfig && flag.Name != util.HandyCiFlagHelp {
+			names = append(names, flag.Name)
+		}
+	})
+
+	return names
+}
+
+// applyFlagDefaults sets the root flags not given on command line from HANDY_CI_OPT_<FLAG> env variables, or
+// else from the defaults of config, and records the source of each value.
+func applyFlagDefaults(flags *pflag.FlagSet) {
+	names := defaultedFlags()
+
+	var unknown []string
+	for name := range config.HandyCiConfig.Defaults {
+		if !util.ContainArgs(names, name) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	sort.Strings(unknown)
+
+	for _, name := range unknown {
+		util.Eprintf("Unknown option %s in defaults of config\n", name)
+	}
+
+	for _, name := range names {
+		flag := flags.Lookup(name)
+		if flag == nil {
+			continue
+		}
+
+		if flag.Changed {
+			flagSources[name] = flagSourceCommandLine
+			continue
+		}
+
+		flagSources[name] = flagSourceDefault
+
+		source, value := flagSourceEnv, os.Getenv(flagEnv(name))
+		if value == "" {
+			source, value = flagSourceConfig, config.HandyCiConfig.Defaults[name]
+		}
+
+		if value == "" {
+			continue
+		}
+
+		// Defaults don't mark flags changed, changed flags are the ones given on command line.
+		err := flag.Value.Set(value)
+		if err != nil {
+			util.Eprintf("Invalid %s default of --%s, %v\n", source, name, err)
+			continue
+		}
+
+		flagSources[name] = source
+	}
+}
+
+var configFlagsCommand = &cobra.Command{
+	Use:          "flags",
+	Short:        "Show effective values of options and where they come from",
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		writer := tabwriter.NewWriter(command.OutOrStdout(), 0, 4, 2, ' ', 0)
+
+		fmt.Fprintln(writer, "OPTION\tVALUE\tSOURCE")
+
+		for _, name := range defaultedFlags() {
+			flag := command.Flags().Lookup(name)
+			if flag == nil {
+				continue
+			}
+
+			source := flagSources[name]
+			if source == flagSourceEnv {
+				source += " " + flagEnv(name)
+			}
+
+			fmt.Fprintf(writer, "--%s\t%s\t%s\n", name, flag.Value.String(), source)
+		}
+
+		return writer.Flush()
+	},
+}
+
+func init() {
+	configCommand.AddCommand(configFlagsCommand)
+}
@@ -0,0 +1,75 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/execution"
+	"github.com/carrchang/handy-ci/util"
+)
+
+func TestApplyFlagDefaults_Precedence(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+
+	config.HandyCiConfig = &config.Config{Defaults: map[string]string{
+		util.HandyCiFlagWorkspace: "from-config",
+		util.HandyCiFlagTags:      "from-config",
+		util.HandyCiFlagContinue:  "true",
+	}}
+
+	t.Setenv("HANDY_CI_OPT_TAGS", "from-env")
+	t.Setenv("HANDY_CI_OPT_DRY_RUN", "true")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String(util.HandyCiFlagWorkspace, "", "")
+	flags.String(util.HandyCiFlagTags, "", "")
+	flags.String(util.HandyCiFlagSkip, "", "")
+	flags.Bool(util.HandyCiFlagContinue, false, "")
+	flags.Bool(util.HandyCiFlagDryRun, false, "")
+	flags.Set(util.HandyCiFlagWorkspace, "from-command-line")
+
+	applyFlagDefaults(flags)
+
+	expectations := map[string][2]string{
+		util.HandyCiFlagWorkspace: {"from-command-line", flagSourceCommandLine},
+		util.HandyCiFlagTags:      {"from-env", flagSourceEnv},
+		util.HandyCiFlagContinue:  {"true", flagSourceConfig},
+		util.HandyCiFlagDryRun:    {"true", flagSourceEnv},
+		util.HandyCiFlagSkip:      {"", flagSourceDefault},
+	}
+
+	for name, expected := range expectations {
+		if value := flags.Lookup(name).Value.String(); value != expected[0] || flagSources[name] != expected[1] {
+			t.Fatalf("expected %s to be %q from %s, got %q from %s", name, expected[0], expected[1], value, flagSources[name])
+		}
+	}
+}
+
+func TestApplyFlagDefaults_IgnoresExecutionEnv(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+
+	config.HandyCiConfig = &config.Config{}
+
+	t.Setenv(execution.EnvWorkspace, "from-execution")
+	t.Setenv(execution.EnvGroup, "from-execution")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String(util.HandyCiFlagWorkspace, "", "")
+	flags.String(util.HandyCiFlagGroup, "", "")
+
+	applyFlagDefaults(flags)
+
+	for _, name := range []string{util.HandyCiFlagWorkspace, util.HandyCiFlagGroup} {
+		if value := flags.Lookup(name).Value.String(); value != "" || flagSources[name] != flagSourceDefault {
+			t.Fatalf("expected %s to be default, got %q from %s", name, value, flagSources[name])
+		}
+	}
+
+	if flagEnv(util.HandyCiFlagWorkspace) == execution.EnvWorkspace || flagEnv(util.HandyCiFlagGroup) == execution.EnvGroup {
+		t.Fatalf("expected option env variables apart from execution env variables")
+	}
+}
@@ -10,8 +10,10 @@ var gitCommand = &cobra.Command{
 	Use:                "git",
 	Short:              "Execute git command",
 	DisableFlagParsing: true,
-	Run: func(command *cobra.Command, args []string) {
-		execution.Execute(command, args, execution.GitExecution{})
+	SilenceUsage:       true,
+	SilenceErrors:      true,
+	RunE: func(command *cobra.Command, args []string) error {
+		return execution.Execute(command, args, execution.GitExecution{})
 	},
 }
 
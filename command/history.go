@@ -0,0 +1,183 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/carrchang/handy-ci/execution"
+	"github.com/carrchang/handy-ci/util"
+)
+
+var historyCommand = &cobra.Command{
+	Use:          "history [REPOSITORY]",
+	Short:        "List runs recorded in history, or show a run",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		runs, err := execution.ReadHistory(execution.HistoryFile())
+		if err != nil {
+			return err
+		}
+
+		var repository string
+		if len(args) > 0 {
+			repository = args[0]
+		}
+
+		id, _ := command.Flags().GetString(util.HandyCiFlagRun)
+		if id != "" {
+			for _, run := range runs {
+				if run.ID == id {
+					return showRun(command.OutOrStdout(), run, repository)
+				}
+			}
+
+			return fmt.Errorf("run %s not in history", id)
+		}
+
+		failed, _ := command.Flags().GetBool(util.HandyCiFlagFailed)
+		since, _ := command.Flags().GetDuration(util.HandyCiFlagSince)
+		limit, _ := command.Flags().GetInt(util.HandyCiFlagLimit)
+
+		runs = filterRuns(runs, repository, since, time.Now())
+
+		if failed {
+			runs = slices.DeleteFunc(runs, func(run execution.RunManifest) bool {
+				return runFailures(run, repository) == 0
+			})
+		}
+
+		if limit > 0 && len(runs) > limit {
+			runs = runs[len(runs)-limit:]
+		}
+
+		return listHistory(command.OutOrStdout(), runs, repository)
+	},
+}
+
+// filterRuns returns the runs started since before now, and which ran repository when it's given.
+func filterRuns(runs []execution.RunManifest, repository string, since time.Duration,
+	now time.Time) []execution.RunManifest {
+	var filtered []execution.RunManifest
+
+	for _, run := range runs {
+		if since > 0 && run.Started.Before(now.Add(-since)) {
+			continue
+		}
+
+		if repository != "" && !slices.ContainsFunc(run.Repositories, func(result execution.RepositoryResult) bool {
+			return strings.EqualFold(result.Repository, repository)
+		}) {
+			continue
+		}
+
+		filtered = append(filtered, run)
+	}
+
+	return filtered
+}
+
+// runFailures returns the number of repositories which failed in run, only repository when it's given.
+func runFailures(run execution.RunManifest, repository string) int {
+	var failures int
+
+	for _, result := range run.Repositories {
+		if (repository == "" || strings.EqualFold(result.Repository, repository)) && result.Status.IsFailure() {
+			failures++
+		}
+	}
+
+	return failures
+}
+
+// listHistory writes runs newest first.
+func listHistory(out io.Writer, runs []execution.RunManifest, repository string) error {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "RUN\tSTARTED\tDURATION\tREPOSITORIES\tFAILED\tCOMMAND")
+
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+
+		var duration time.Duration
+		if run.Finished != nil {
+			duration = run.Finished.Sub(run.Started)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%d\t%s\n", run.ID, run.Started.Format(time.DateTime),
+			roundDuration(duration), len(run.Repositories), runFailures(run, repository),
+			util.HandyCiName+" "+strings.Join(run.Args, " "))
+	}
+
+	return writer.Flush()
+}
+
+// showRun writes run with the executions in its repositories, only repository when it's given.
+func showRun(out io.Writer, run execution.RunManifest, repository string) error {
+	fmt.Fprintf(out, "RUN: %s\n", run.ID)
+	fmt.Fprintf(out, "COMMAND: %s %s\n", util.HandyCiName, strings.Join(run.Args, " "))
+	fmt.Fprintf(out, "STARTED: %s\n", run.Started.Format(time.DateTime))
+
+	if run.Finished != nil {
+		fmt.Fprintf(out, "DURATION: %s\n", roundDuration(run.Finished.Sub(run.Started)))
+	}
+
+	fmt.Fprintf(out, "SELECTED: %d repositories\n\n", len(run.Selection))
+
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "REPOSITORY\tSTATUS\tHEAD\tSCRIPT\tSTEP\tEXIT CODE\tDURATION")
+
+	for _, result := range run.Repositories {
+		if repository != "" && !strings.EqualFold(result.Repository, repository) {
+			continue
+		}
+
+		head := result.Head
+		if len(head) > 12 {
+			head = head[:12]
+		}
+
+		label := result.Group + "/" + result.Repository
+
+		if len(result.Executions) == 0 {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t-\t-\t-\t-\n", label, result.Status, head)
+		}
+
+		for _, executionResult := range result.Executions {
+			step := executionResult.Step
+			if step == "" {
+				step = "-"
+			}
+
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", label, executionResult.Status, head,
+				executionResult.Script, step, executionResult.ExitCode, roundDuration(executionResult.Duration))
+		}
+	}
+
+	return writer.Flush()
+}
+
+// roundDuration rounds duration to what matters when reading it, seconds from a second on.
+func roundDuration(duration time.Duration) time.Duration {
+	if duration >= time.Second {
+		return duration.Round(time.Second)
+	}
+
+	return duration.Round(time.Millisecond)
+}
+
+func init() {
+	rootCommand.AddCommand(historyCommand)
+
+	historyCommand.Flags().String(util.HandyCiFlagRun, "", "Run to show with its executions")
+	historyCommand.Flags().Bool(util.HandyCiFlagFailed, false, "Only list runs which failed")
+	historyCommand.Flags().Duration(util.HandyCiFlagSince, 0, "Only list runs started within the duration, such as 24h")
+	historyCommand.Flags().Int(util.HandyCiFlagLimit, 20, "Number of runs listed, 0 lists all")
+}
@@ -0,0 +1,62 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carrchang/handy-ci/execution"
+)
+
+func historyRuns() []execution.RunManifest {
+	started := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	finished := started.Add(90 * time.Second)
+
+	return []execution.RunManifest{
+		{ID: "1", Args: []string{"exec", "mvn"}, Started: started.Add(-48 * time.Hour), Repositories: []execution.RepositoryResult{
+			{Group: "g", Repository: "a", Status: execution.StatusSucceeded},
+		}},
+		{ID: "2", Args: []string{"exec", "mvn"}, Started: started, Finished: &finished, Selection: []string{"ws/g/a", "ws/g/b"},
+			Repositories: []execution.RepositoryResult{
+				{Group: "g", Repository: "a", Status: execution.StatusSucceeded, Head: "0123456789abcdef",
+					Executions: []execution.ExecutionResult{{Script: "mvn", Status: execution.StatusSucceeded, Duration: time.Minute}}},
+				{Group: "g", Repository: "b", Status: execution.StatusFailed,
+					Executions: []execution.ExecutionResult{{Script: "mvn", Step: "test", Status: execution.StatusFailed, ExitCode: 1, Duration: 30 * time.Second}}},
+			}},
+	}
+}
+
+func TestFilterRuns(t *testing.T) {
+	now := time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)
+
+	if runs := filterRuns(historyRuns(), "", 24*time.Hour, now); len(runs) != 1 || runs[0].ID != "2" {
+		t.Fatalf("expected run since a day, got %+v", runs)
+	}
+
+	if runs := filterRuns(historyRuns(), "B", 0, now); len(runs) != 1 || runs[0].ID != "2" {
+		t.Fatalf("expected run of repository b, got %+v", runs)
+	}
+
+	if failures := runFailures(historyRuns()[1], "a"); failures != 0 {
+		t.Fatalf("expected no failure of a, got %d", failures)
+	}
+}
+
+func TestShowRun(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	if err := showRun(out, historyRuns()[1], ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"COMMAND: handy-ci exec mvn\n", "DURATION: 1m30s\n", "SELECTED: 2 repositories\n",
+		"g/a         succeeded  0123456789ab  mvn     -     0          1m0s",
+		"g/b         failed                   mvn     test  1          30s",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("expected %q in %q", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/execution"
+	"github.com/carrchang/handy-ci/util"
+)
+
+// listedRepository is the data of --format templates. Path is the resolved repository path, the
+// configured path is available as .Repository.Path.
+type listedRepository struct {
+	config.Repository
+	Workspace     config.Workspace
+	Group         config.Group
+	Path          string
+	WorkspacePath string
+	GroupPath     string
+}
+
+var listCommand = &cobra.Command{
+	Use:          "list",
+	Short:        "List workspaces, groups and repositories",
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		format, _ := command.Flags().GetString(util.HandyCiFlagFormat)
+
+		var repositories []listedRepository
+
+		for _, target := range execution.Selection(command) {
+			repositories = append(repositories, listedRepository{
+				Repository:    target.Repository,
+				Workspace:     target.Workspace,
+				Group:         target.Group,
+				Path:          execution.RepositoryPath(target.Workspace, target.Group, target.Repository),
+				WorkspacePath: execution.WorkspacePath(target.Workspace),
+				GroupPath:     execution.GroupPath(target.Workspace, target.Group),
+			})
+		}
+
+		if format != "" {
+			return listFormatted(command.OutOrStdout(), format, repositories)
+		}
+
+		listTree(command.OutOrStdout(), repositories)
+
+		return nil
+	},
+}
+
+func listFormatted(out io.Writer, format string, repositories []listedRepository) error {
+	formatTemplate, err := template.New("format").Funcs(template.FuncMap{"join": strings.Join}).Parse(format)
+	if err != nil {
+		return err
+	}
+
+	for _, repository := range repositories {
+		err = formatTemplate.Execute(out, repository)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out)
+	}
+
+	return nil
+}
+
+func listTree(out io.Writer, repositories []listedRepository) {
+	var currentWorkspace, currentGroup string
+
+	for _, repository := range repositories {
+		if repository.Workspace.Name != currentWorkspace {
+			fmt.Fprintf(out, "%s  %s\n", repository.Workspace.Name, repository.WorkspacePath)
+
+			currentWorkspace = repository.Workspace.Name
+			currentGroup = ""
+		}
+
+		if repository.Group.Name != currentGroup {
+			fmt.Fprintf(out, "  %s  %s\n", repository.Group.Name, repository.GroupPath)
+
+			currentGroup = repository.Group.Name
+		}
+
+		fmt.Fprintf(out, "    %s  %s\n", repository.Name, repository.Path)
+
+		if len(repository.Tags) > 0 {
+			fmt.Fprintf(out, "      tags: %s\n", strings.Join(repository.Tags, ", "))
+		}
+
+		if len(repository.Scripts) > 0 {
+			var scripts []string
+
+			for _, script := range repository.Scripts {
+				description := script.Name
+
+				if script.Default {
+					description += " (default)"
+				}
+
+				if len(script.Paths) > 0 {
+					description += " [" + strings.Join(script.Paths, ", ") + "]"
+				}
+
+				scripts = append(scripts, description)
+			}
+
+			fmt.Fprintf(out, "      scripts: %s\n", strings.Join(scripts, ", "))
+		}
+
+		for _, remote := range repository.Remotes {
+			fmt.Fprintf(out, "      remote: %s %s\n", remote.Name, remote.URL)
+		}
+	}
+}
+
+func init() {
+	rootCommand.AddCommand(listCommand)
+
+	listCommand.Flags().String(
+		util.HandyCiFlagFormat, "", "Print each repository with Go template, e.g. '{{.Path}}' or '{{join .Tags \",\"}}'")
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/util"
+)
+
+func TestListCommand_TreeAndFormat(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+
+	config.HandyCiConfig = &config.Config{Workspaces: []config.Workspace{{Name: "keepnative", Path: "/coding/keepnative", Groups: []config.Group{
+		{Name: "next", Repositories: []config.Repository{
+			{Name: "java", Tags: []string{"java"}, Scripts: []config.Script{{Name: "mvn", Default: true}}},
+			{Name: "soupe"},
+		}},
+		{Name: "spring-cloud", Repositories: []config.Repository{{Name: "data-flow"}}},
+	}}}}
+
+	buf := &bytes.Buffer{}
+	listCommand.SetOut(buf)
+	if err := listCommand.RunE(listCommand, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"keepnative  /coding/keepnative", "  next  /coding/keepnative/next", "    java  /coding/keepnative/next/java", "scripts: mvn (default)", "data-flow"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("expected %q in output:\n%s", expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	listCommand.InheritedFlags() // merge persistent flags of root command, as cobra does when parsing
+	listCommand.Flags().Set(util.HandyCiFlagGroup, "next")
+	listCommand.Flags().Set(util.HandyCiFlagFormat, "{{.Name}} {{.Path}}")
+	defer listCommand.Flags().Set(util.HandyCiFlagGroup, "")
+	defer listCommand.Flags().Set(util.HandyCiFlagFormat, "")
+	if err := listCommand.RunE(listCommand, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "java /coding/keepnative/next/java\nsoupe /coding/keepnative/next/soupe\n" {
+		t.Fatalf("unexpected formatted output: %q", buf.String())
+	}
+}
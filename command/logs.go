@@ -0,0 +1,169 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/carrchang/handy-ci/execution"
+	"github.com/carrchang/handy-ci/util"
+)
+
+// followInterval is how often the logs of a run in progress are checked for new output.
+const followInterval = 500 * time.Millisecond
+
+var logsCommand = &cobra.Command{
+	Use:          "logs [REPOSITORY]",
+	Short:        "Show logs of executions of the last or a given run",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		root := execution.LogsDirectory()
+
+		list, _ := command.Flags().GetBool(util.HandyCiFlagList)
+		if list {
+			return listRuns(command.OutOrStdout(), root)
+		}
+
+		id, _ := command.Flags().GetString(util.HandyCiFlagRun)
+		failed, _ := command.Flags().GetBool(util.HandyCiFlagFailed)
+		follow, _ := command.Flags().GetBool(util.HandyCiFlagFollow)
+
+		if id == "" {
+			ids, err := execution.RunIDs(root)
+			if err != nil {
+				return err
+			}
+
+			if len(ids) == 0 {
+				return fmt.Errorf("no logs in %s", root)
+			}
+
+			id = ids[len(ids)-1]
+		}
+
+		match := func(result execution.RepositoryResult) bool {
+			if len(args) > 0 && !strings.EqualFold(result.Repository, args[0]) {
+				return false
+			}
+
+			return !failed || result.Status.IsFailure()
+		}
+
+		return showLogs(command.OutOrStdout(), root, id, match, follow)
+	},
+}
+
+// listRuns writes the runs with logs in root, newest first.
+func listRuns(out io.Writer, root string) error {
+	ids, err := execution.RunIDs(root)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "RUN\tSTARTED\tREPOSITORIES\tFAILED\tCOMMAND")
+
+	for i := len(ids) - 1; i >= 0; i-- {
+		manifest, err := execution.ReadRunManifest(root, ids[i])
+		if err != nil {
+			continue
+		}
+
+		var failures int
+		for _, result := range manifest.Repositories {
+			if result.Status.IsFailure() {
+				failures++
+			}
+		}
+
+		command := util.HandyCiName + " " + strings.Join(manifest.Args, " ")
+		if manifest.Finished == nil {
+			command += " (running)"
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%s\n",
+			manifest.ID, manifest.Started.Format(time.DateTime), len(manifest.Repositories), failures, command)
+	}
+
+	return writer.Flush()
+}
+
+// showLogs writes the logs of the repositories of run id matching match, in the order they were executed.
+// When following, logs are written as they grow until the run finishes.
+func showLogs(
+	out io.Writer, root string, id string, match func(execution.RepositoryResult) bool, follow bool) error {
+	offsets := map[string]int64{}
+
+	for {
+		manifest, err := execution.ReadRunManifest(root, id)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range manifest.Repositories {
+			// Repositories filtered by --failed are shown once they are done.
+			if !match(result) {
+				continue
+			}
+
+			for _, log := range result.Logs {
+				offset, shown := offsets[log]
+				if !shown {
+					fmt.Fprintf(out, "==> %s (%s) <==\n", filepath.ToSlash(log), result.Status)
+				}
+
+				offsets[log], err = copyFrom(out, filepath.Join(root, id, log), offset)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Logs are complete once the manifest read says the run finished.
+		if !follow || manifest.Finished != nil {
+			return nil
+		}
+
+		time.Sleep(followInterval)
+	}
+}
+
+// copyFrom writes the content of file from offset to out, and returns the offset of its end.
+func copyFrom(out io.Writer, file string, offset int64) (int64, error) {
+	log, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return offset, nil
+	}
+
+	if err != nil {
+		return offset, err
+	}
+
+	defer log.Close()
+
+	_, err = log.Seek(offset, io.SeekStart)
+	if err != nil {
+		return offset, err
+	}
+
+	written, err := io.Copy(out, log)
+
+	return offset + written, err
+}
+
+func init() {
+	rootCommand.AddCommand(logsCommand)
+
+	logsCommand.Flags().String(util.HandyCiFlagRun, "", "Run to show logs of, the last run when not given")
+	logsCommand.Flags().Bool(util.HandyCiFlagFailed, false, "Only show logs of failed repositories")
+	logsCommand.Flags().BoolP(util.HandyCiFlagFollow, "f", false, "Keep showing logs as a run in progress writes them")
+	logsCommand.Flags().Bool(util.HandyCiFlagList, false, "List runs with logs")
+}
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carrchang/handy-ci/execution"
+)
+
+func writeRun(t *testing.T, root string, manifest execution.RunManifest, logs map[string]string) {
+	for path, content := range logs {
+		os.MkdirAll(filepath.Dir(filepath.Join(root, manifest.ID, path)), 0755)
+		os.WriteFile(filepath.Join(root, manifest.ID, path), []byte(content), 0644)
+	}
+
+	content, _ := json.Marshal(manifest)
+	if err := os.WriteFile(filepath.Join(root, manifest.ID, "manifest.json"), content, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestShowLogs_Failed(t *testing.T) {
+	root := t.TempDir()
+	finished := time.Now()
+
+	writeRun(t, root, execution.RunManifest{ID: "run", Finished: &finished, Repositories: []execution.RepositoryResult{
+		{Repository: "a", Status: execution.StatusSucceeded, Logs: []string{"ws/g/a.log"}},
+		{Repository: "b", Status: execution.StatusFailed, Logs: []string{"ws/g/b.log"}},
+	}}, map[string]string{"ws/g/a.log": "a output\n", "ws/g/b.log": "b output\n"})
+
+	out := &bytes.Buffer{}
+	match := func(result execution.RepositoryResult) bool { return result.Status.IsFailure() }
+
+	if err := showLogs(out, root, "run", match, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "==> ws/g/b.log (failed) <==\nb output\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestShowLogs_Follow(t *testing.T) {
+	root := t.TempDir()
+
+	running := execution.RunManifest{ID: "run", Repositories: []execution.RepositoryResult{
+		{Repository: "a", Status: execution.StatusRunning, Logs: []string{"ws/g/a.log"}},
+	}}
+	writeRun(t, root, running, map[string]string{"ws/g/a.log": "first\n"})
+
+	go func() {
+		time.Sleep(2 * followInterval)
+
+		finished := time.Now()
+		running.Finished = &finished
+		running.Repositories[0].Status = execution.StatusSucceeded
+		writeRun(t, root, running, map[string]string{"ws/g/a.log": "first\nsecond\n"})
+	}()
+
+	out := &bytes.Buffer{}
+	match := func(execution.RepositoryResult) bool { return true }
+
+	if err := showLogs(out, root, "run", match, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(out.String(), "first\nsecond\n") || strings.Count(out.String(), "first") != 1 {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
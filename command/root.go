@@ -4,14 +4,14 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/execution"
 	"github.com/carrchang/handy-ci/util"
 )
 
-var cfgFile string
-
 // rootCommand represents the base command when called without any subcommands
 var rootCommand = &cobra.Command{
 	Use:                util.HandyCiName,
@@ -39,10 +39,16 @@ Commands:
 Options:
 {{.Flags.FlagUsages | trimTrailingWhitespaces}}
 
-Options can be in front of, behind, or on both sides of the command.
+Options can be in front of, behind, or on both sides of the command, except --quiet, --show-if,
+--progress, --output, --prefix, --keep-logs, --retry, --timeout, --deadline, --fail-at-end and
+--fail-fast, which commands often have too: they are options of Handy CI in front of the command only,
+behind it they go to the command.
 
 Original options of any command can be as additional options, and be in behind of the command.
 
+Options take --option=value, -Ovalue and bundled forms such as -CR repository, arguments after -- are
+passed to the command as they are.
+
 {{- if .HasAvailableSubCommands}}
 
 Use "{{.CommandPath}} COMMAND --help" for more information about a command.
@@ -57,12 +63,17 @@ var helpTemplate = `
 // This is called by main.main(). It only needs to happen once to the rootCommand.
 func Execute() {
 	if err := rootCommand.Execute(); err != nil {
+		// Interrupted and timed out runs exit like their shell equivalents.
+		if exitCoder, ok := err.(interface{ ExitCode() int }); ok {
+			os.Exit(exitCoder.ExitCode())
+		}
+
 		os.Exit(1)
 	}
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	rootCommand.PersistentPreRun = loadConfig
 
 	rootCommand.CompletionOptions.DisableDefaultCmd = true
 
@@ -83,11 +94,38 @@ func init() {
 		"", "Execute command in comma-delimited list of repositories")
 	rootCommand.PersistentFlags().String(util.HandyCiFlagTags, "", "Filter repositories by tags in comma-delimited list")
 	rootCommand.PersistentFlags().StringP(
-		util.HandyCiFlagFrom, util.HandyCiFlagFromShorthand, "", "Execute command from repository to end")
+		util.HandyCiFlagFrom, util.HandyCiFlagFromShorthand, "", "Execute command from repository to end of selection")
 	rootCommand.PersistentFlags().String(util.HandyCiFlagSkip, "", "Skip execution in comma-delimited list of repositories")
 
 	rootCommand.PersistentFlags().BoolP(
 		util.HandyCiFlagContinue, util.HandyCiFlagContinueShorthand, false, "Skip failed command and continue")
+	rootCommand.PersistentFlags().Bool(
+		util.HandyCiFlagFailAtEnd, false, "Continue after failures, skip repositories depending on failed ones, fail at end")
+	rootCommand.PersistentFlags().Bool(
+		util.HandyCiFlagFailFast, false, "Stop at the first failure, also when --continue is set by default")
+	rootCommand.PersistentFlags().Bool(
+		util.HandyCiFlagIncludeArchived, false, "Execute command in disabled and archived repositories too")
+	rootCommand.PersistentFlags().Duration(
+		util.HandyCiFlagTimeout, 0, "Stop executions taking longer, such as 10m, unless their script has a timeout")
+	rootCommand.PersistentFlags().Duration(util.HandyCiFlagDeadline, 0, "Stop the whole run after the duration")
+	rootCommand.PersistentFlags().Int(
+		util.HandyCiFlagRetry, 0, "Retry failed executions up to N times, unless their script has a retry")
+	rootCommand.PersistentFlags().Bool(
+		util.HandyCiFlagPrefix, false, "Prefix output lines of commands with group and repository")
+	rootCommand.PersistentFlags().Int(
+		util.HandyCiFlagKeepLogs, execution.DefaultKeptLogs, "Number of runs whose logs are kept, 0 disables logs")
+	rootCommand.PersistentFlags().Int(
+		util.HandyCiFlagKeepHistory, execution.DefaultKeptHistory, "Number of runs kept in history, 0 disables history")
+	rootCommand.PersistentFlags().Bool(
+		util.HandyCiFlagQuiet, false, "Only show output of failed repositories, and a status line for the others")
+	rootCommand.PersistentFlags().String(
+		util.HandyCiFlagShowIf, "", "Also show output of repositories matching the regular expression, implies --quiet")
+	rootCommand.PersistentFlags().Bool(
+		util.HandyCiFlagProgress, false, "Show progress of the run in the terminal instead of the output of commands")
+	rootCommand.PersistentFlags().String(
+		util.HandyCiFlagOutput, execution.OutputText, "Format of the result of the run, text or json, json writes the rest to stderr")
+	rootCommand.PersistentFlags().Bool(
+		util.HandyCiFlagNonInteractive, false, "Don't prompt what to do when an execution fails in a terminal")
 
 	configFlagUsage := "Config file (default is " + util.Home() +
 		string(os.PathSeparator) + ".handy-ci" + string(os.PathSeparator) + "config.yaml)"
@@ -96,9 +134,31 @@ func init() {
 	rootCommand.PersistentFlags().Bool(util.HandyCiFlagDryRun, false, "Only print the command and execution path")
 	rootCommand.PersistentFlags().Bool(util.HandyCiFlagHelp, false, "Print usage")
 	rootCommand.PersistentFlags().Lookup(util.HandyCiFlagHelp).Hidden = true
+
+	execution.FrontOnly(
+		rootCommand.PersistentFlags(),
+		util.HandyCiFlagQuiet, util.HandyCiFlagShowIf, util.HandyCiFlagProgress, util.HandyCiFlagOutput,
+		util.HandyCiFlagPrefix, util.HandyCiFlagKeepLogs, util.HandyCiFlagRetry, util.HandyCiFlagTimeout,
+		util.HandyCiFlagDeadline, util.HandyCiFlagFailAtEnd, util.HandyCiFlagFailFast)
+}
+
+// loadConfig loads config and applies flag defaults before any command runs.
+func loadConfig(command *cobra.Command, args []string) {
+	// exec and git parse their flags when executed, the config flag is needed before.
+	if command.DisableFlagParsing {
+		execution.ParseFlagsAndArgs(command.Flags(), args)
+	}
+
+	initConfig(command.Flags())
+	applyFlagDefaults(command.Flags())
 }
 
-func initConfig() {
+func initConfig(flags *pflag.FlagSet) {
+	cfgFile, _ := flags.GetString(util.HandyCiFlagConfig)
+	if cfgFile == "" {
+		cfgFile = os.Getenv(flagEnv(util.HandyCiFlagConfig))
+	}
+
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
@@ -109,9 +169,9 @@ func initConfig() {
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err != nil {
-		util.Println(err)
+		util.Eprintln(err)
 	} else {
-		util.Println("Using config file:", viper.ConfigFileUsed())
+		util.Eprintln("Using config file:", viper.ConfigFileUsed())
 	}
 
 	config.Initialize()
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/carrchang/handy-ci/execution"
 	"github.com/carrchang/handy-ci/util"
 )
 
@@ -37,3 +38,19 @@ func TestRootCommand_SubcommandsRegistered(t *testing.T) {
 		t.Fatalf("expected exec (%v) and git (%v) to be registered", foundExec, foundGit)
 	}
 }
+
+func TestRootCommand_FlagsOfCommandsFrontOnly(t *testing.T) {
+	for _, args := range [][]string{
+		{"npm", "install", "--prefix", "./web"},
+		{"git", "archive", "--prefix=src/", "HEAD"},
+		{"grep", "--show-if", "x"},
+		{"tool", "--keep-logs", "3"},
+		{"curl", "--retry", "3", "http://x"},
+		{"tool", "--timeout", "3", "--deadline=5"},
+	} {
+		cleaned, err := execution.ParseFlagsAndArgs(rootCommand.PersistentFlags(), args)
+		if err != nil || strings.Join(cleaned, " ") != strings.Join(args, " ") {
+			t.Fatalf("expected %v passed on to the command, got %v, %v", args, cleaned, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/carrchang/handy-ci/execution"
+	"github.com/carrchang/handy-ci/util"
+)
+
+// trendSamples is the minimum number of runs of a script in a repository its trend is computed from.
+const trendSamples = 4
+
+var statsCommand = &cobra.Command{
+	Use:          "stats [REPOSITORY]",
+	Short:        "Show durations and failure rates of scripts per repository from history",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(command *cobra.Command, args []string) error {
+		runs, err := execution.ReadHistory(execution.HistoryFile())
+		if err != nil {
+			return err
+		}
+
+		var repository string
+		if len(args) > 0 {
+			repository = args[0]
+		}
+
+		since, _ := command.Flags().GetDuration(util.HandyCiFlagSince)
+
+		return writeStats(command.OutOrStdout(), scriptStats(filterRuns(runs, repository, since, time.Now()), repository))
+	},
+}
+
+// scriptStat is the statistics of the runs of a script in a repository.
+type scriptStat struct {
+	repository string
+	script     string
+	durations  []time.Duration
+	failures   int
+}
+
+// scriptStats returns the statistics of scripts per repository in runs, only repository when it's given,
+// slowest first. The duration of a script in a run is the sum of its executions in the repository, runs
+// interrupted are left out.
+func scriptStats(runs []execution.RunManifest, repository string) []*scriptStat {
+	stats := map[string]*scriptStat{}
+
+	var keys []string
+
+	for _, run := range runs {
+		for _, result := range run.Repositories {
+			if repository != "" && !strings.EqualFold(result.Repository, repository) ||
+				result.Status == execution.StatusInterrupted {
+				continue
+			}
+
+			label := result.Group + "/" + result.Repository
+			durations := map[string]time.Duration{}
+
+			var scripts []string
+
+			for _, executionResult := range result.Executions {
+				if _, found := durations[executionResult.Script]; !found {
+					scripts = append(scripts, executionResult.Script)
+				}
+
+				durations[executionResult.Script] += executionResult.Duration
+			}
+
+			for _, script := range scripts {
+				key := result.Workspace + "/" + label + "\x00" + script
+
+				stat, found := stats[key]
+				if !found {
+					stat = &scriptStat{repository: label, script: script}
+					stats[key] = stat
+					keys = append(keys, key)
+				}
+
+				stat.durations = append(stat.durations, durations[script])
+
+				if result.Status.IsFailure() {
+					stat.failures++
+				}
+			}
+		}
+	}
+
+	var sorted []*scriptStat
+	for _, key := range keys {
+		sorted = append(sorted, stats[key])
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return percentile(sorted[i].durations, 50) > percentile(sorted[j].durations, 50)
+	})
+
+	return sorted
+}
+
+// percentile returns the p-th percentile of durations by nearest rank.
+func percentile(durations []time.Duration, p int) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := (p*len(sorted) + 99) / 100
+
+	return sorted[max(rank, 1)-1]
+}
+
+// trend returns how the median duration of the newer half of durations changed from the older half, as a
+// percentage, empty when there are too few durations to tell.
+func trend(durations []time.Duration) string {
+	if len(durations) < trendSamples {
+		return ""
+	}
+
+	older := percentile(durations[:len(durations)/2], 50)
+	newer := percentile(durations[len(durations)/2:], 50)
+
+	if older == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%+d%%", int((newer-older)*100/older))
+}
+
+func writeStats(out io.Writer, stats []*scriptStat) error {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "REPOSITORY\tSCRIPT\tRUNS\tP50\tP95\tFAILURE RATE\tTREND")
+
+	for _, stat := range stats {
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%s\t%s\t%d%%\t%s\n", stat.repository, stat.script, len(stat.durations),
+			roundDuration(percentile(stat.durations, 50)), roundDuration(percentile(stat.durations, 95)),
+			stat.failures*100/len(stat.durations), trend(stat.durations))
+	}
+
+	return writer.Flush()
+}
+
+func init() {
+	rootCommand.AddCommand(statsCommand)
+
+	statsCommand.Flags().Duration(util.HandyCiFlagSince, 0, "Only count runs started within the duration, such as 168h")
+}
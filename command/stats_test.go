@@ -0,0 +1,73 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carrchang/handy-ci/execution"
+)
+
+func TestPercentile(t *testing.T) {
+	var durations []time.Duration
+	for i := 10; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Second)
+	}
+
+	if p50 := percentile(durations, 50); p50 != 5*time.Second {
+		t.Fatalf("unexpected p50 %s", p50)
+	}
+
+	if p95 := percentile(durations, 95); p95 != 10*time.Second {
+		t.Fatalf("unexpected p95 %s", p95)
+	}
+
+	if p := percentile(nil, 50); p != 0 {
+		t.Fatalf("unexpected percentile of nothing %s", p)
+	}
+}
+
+func TestTrend(t *testing.T) {
+	if trend([]time.Duration{time.Second, time.Second, time.Second}) != "" {
+		t.Fatalf("expected no trend from 3 runs")
+	}
+
+	if got := trend([]time.Duration{10 * time.Second, 10 * time.Second, 12 * time.Second, 12 * time.Second}); got != "+20%" {
+		t.Fatalf("unexpected trend %s", got)
+	}
+}
+
+func TestScriptStats(t *testing.T) {
+	run := func(status execution.Status, durations ...time.Duration) execution.RunManifest {
+		result := execution.RepositoryResult{Workspace: "ws", Group: "g", Repository: "a", Status: status}
+
+		for _, duration := range durations {
+			result.Executions = append(result.Executions, execution.ExecutionResult{Script: "build", Duration: duration})
+		}
+
+		return execution.RunManifest{Repositories: []execution.RepositoryResult{
+			result,
+			{Workspace: "ws", Group: "g", Repository: "b", Status: execution.StatusSucceeded,
+				Executions: []execution.ExecutionResult{{Script: "git pull", Duration: time.Second}}},
+		}}
+	}
+
+	stats := scriptStats([]execution.RunManifest{
+		run(execution.StatusSucceeded, time.Minute, time.Minute),
+		run(execution.StatusFailed, time.Minute),
+		run(execution.StatusInterrupted, time.Second),
+	}, "")
+
+	if len(stats) != 2 || stats[0].repository != "g/a" || stats[0].failures != 1 || len(stats[0].durations) != 2 ||
+		stats[0].durations[0] != 2*time.Minute || stats[1].script != "git pull" || len(stats[1].durations) != 3 {
+		t.Fatalf("unexpected stats %+v %+v", stats[0], stats[1])
+	}
+
+	out := &bytes.Buffer{}
+	writeStats(out, stats)
+
+	if !strings.Contains(out.String(), "g/a         build     2     1m0s  2m0s  50%") {
+		t.Fatalf("unexpected stats %q", out.String())
+	}
+}
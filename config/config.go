@@ -1,7 +1,11 @@
 package config
 
 import (
+	"path/filepath"
+	"strings"
+
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 
 	"github.com/carrchang/handy-ci/util"
 )
@@ -9,51 +13,264 @@ import (
 var HandyCiConfig *Config
 
 type Config struct {
-	ScriptDefinitions []ScriptDefinition `yaml:"scriptDefinitions"`
-	Workspaces        []Workspace        `yaml:"workspaces"`
+	Version             int                `yaml:"version,omitempty" description:"Schema version of the configuration"`
+	Defaults            map[string]string  `yaml:"defaults,omitempty" description:"Default values of command line options, by option name"`
+	ScriptDefinitions   []ScriptDefinition `yaml:"scriptDefinitions,omitempty" description:"Scripts known to exec command"`
+	RepositoryTemplates []Repository       `yaml:"repositoryTemplates,omitempty" description:"Reusable repository definitions, extended by repositories"`
+	Workspaces          []Workspace        `yaml:"workspaces,omitempty" description:"Workspaces of repositories"`
+	Overlays            []Overlay          `yaml:"overlays,omitempty" description:"Configuration merged on top of the configuration on some hosts or profiles"`
+
+	// AppliedOverlays holds the names of the overlays merged by ApplyOverlays.
+	AppliedOverlays []string `yaml:"-"`
+}
+
+type Overlay struct {
+	Name                string             `yaml:"name" jsonschema:"required,pattern=^[A-Za-z0-9._-]+$" description:"Name of the overlay, selected by HANDY_CI_PROFILE"`
+	When                OverlayCondition   `yaml:"when,omitempty" description:"Conditions of the host the overlay is applied on, all of them must hold"`
+	ScriptDefinitions   []ScriptDefinition `yaml:"scriptDefinitions,omitempty" description:"Script definitions replacing or added to script definitions"`
+	RepositoryTemplates []Repository       `yaml:"repositoryTemplates,omitempty" description:"Repository templates merged into repository templates by name"`
+	Workspaces          []Workspace        `yaml:"workspaces,omitempty" description:"Workspaces merged into workspaces by name, with their groups and repositories"`
+}
+
+type OverlayCondition struct {
+	OS       string            `yaml:"os,omitempty" description:"Operating system, such as linux, darwin or windows"`
+	Hostname string            `yaml:"hostname,omitempty" description:"Hostname, * and ? match any characters"`
+	Env      map[string]string `yaml:"env,omitempty" description:"Environment variables matching the values, * and ? match any characters, an empty value requires the variable to be set"`
 }
 
 type ScriptDefinition struct {
-	Name        string `yaml:"name"`
-	DefaultArgs string `yaml:"defaultArgs"`
+	Name        string            `yaml:"name" jsonschema:"required,pattern=^[A-Za-z0-9._/-]+$" description:"Command name of the script"`
+	DefaultArgs string            `yaml:"defaultArgs,omitempty" description:"Arguments used when the script is executed as default script"`
+	Shell       bool              `yaml:"shell,omitempty" description:"Run the script and its steps through the system shell"`
+	Pre         []Step            `yaml:"pre,omitempty" description:"Steps run before the script"`
+	Steps       []Step            `yaml:"steps,omitempty" description:"Steps run instead of the script command"`
+	Post        []Step            `yaml:"post,omitempty" description:"Steps run after the script"`
+	Env         map[string]string `yaml:"env,omitempty" description:"Environment variables of executions of the script"`
+	EnvFile     string            `yaml:"envFile,omitempty" description:"Dotenv file of executions of the script, relative to repository path"`
+	Timeout     string            `yaml:"timeout,omitempty" jsonschema:"pattern=^([0-9]+(\\.[0-9]+)?(ns|us|ms|s|m|h))+$" description:"Time each execution of the script may take, such as 10m"`
+	Retry       Retry             `yaml:"retry,omitempty" description:"Retry of failed executions of the script"`
+}
+
+type Retry struct {
+	Attempts      int    `yaml:"attempts,omitempty" description:"Number of times executions are run before they are failed"`
+	Backoff       string `yaml:"backoff,omitempty" jsonschema:"pattern=^([0-9]+(\\.[0-9]+)?(ns|us|ms|s|m|h))+$" description:"Wait before the second attempt, doubled before each further attempt, 1s when not set"`
+	OnExitCodes   []int  `yaml:"onExitCodes,omitempty" description:"Exit codes retried, any failure is retried when not set"`
+	OnOutputMatch string `yaml:"onOutputMatch,omitempty" description:"Regular expression, only failures with matching output are retried"`
+}
+
+type Step struct {
+	Name         string `yaml:"name" description:"Name of the step"`
+	Run          string `yaml:"run,omitempty" jsonschema:"required" description:"Command line of the step"`
+	Shell        bool   `yaml:"shell,omitempty" description:"Run the step through the system shell"`
+	AllowFailure bool   `yaml:"allowFailure,omitempty" description:"Continue with remaining steps when the step fails"`
 }
 
 type Workspace struct {
-	Name   string  `yaml:"name"`
-	Path   string  `yaml:"path"`
-	Groups []Group `yaml:"groups"`
+	Name     string            `yaml:"name" jsonschema:"required,pattern=^[A-Za-z0-9._-]+$" description:"Name of the workspace"`
+	Path     string            `yaml:"path,omitempty" description:"Root path of the workspace, $HOME and $HANDY_CI_ROOT are expanded"`
+	Groups   []Group           `yaml:"groups,omitempty" description:"Groups of the workspace"`
+	Env      map[string]string `yaml:"env,omitempty" description:"Environment variables of executions in the workspace"`
+	EnvFile  string            `yaml:"envFile,omitempty" description:"Dotenv file of executions in the workspace, relative to workspace path"`
+	Scripts  []Script          `yaml:"scripts,omitempty" description:"Scripts inherited by groups and repositories"`
+	Tags     []string          `yaml:"tags,omitempty" description:"Tags inherited by groups and repositories"`
+	Remotes  []GitRemote       `yaml:"remotes,omitempty" description:"Remotes inherited by groups and repositories"`
+	Enabled  *bool             `yaml:"enabled,omitempty" description:"Execute commands in repositories of the workspace, true when not set"`
+	Archived *bool             `yaml:"archived,omitempty" description:"Repositories of the workspace are retired and not executed in"`
+	ReadOnly *bool             `yaml:"readOnly,omitempty" description:"Refuse git commands writing to repositories of the workspace"`
 }
 
 type Group struct {
-	Name              string       `yaml:"name"`
-	NameIgnoredInPath bool         `yaml:"nameIgnoredInPath"`
-	Path              string       `yaml:"path"`
-	Repositories      []Repository `yaml:"repositories"`
+	Name              string            `yaml:"name" jsonschema:"required,pattern=^[A-Za-z0-9._-]+$" description:"Name of the group"`
+	NameIgnoredInPath bool              `yaml:"nameIgnoredInPath,omitempty" description:"Don't append group name to workspace path"`
+	Path              string            `yaml:"path,omitempty" description:"Path of the group, relative to workspace path or absolute"`
+	Repositories      []Repository      `yaml:"repositories,omitempty" description:"Repositories of the group"`
+	Groups            []Group           `yaml:"groups,omitempty" description:"Sub-groups of the group, with paths relative to group path"`
+	Env               map[string]string `yaml:"env,omitempty" description:"Environment variables of executions in the group"`
+	EnvFile           string            `yaml:"envFile,omitempty" description:"Dotenv file of executions in the group, relative to group path"`
+	Scripts           []Script          `yaml:"scripts,omitempty" description:"Scripts inherited by repositories"`
+	Tags              []string          `yaml:"tags,omitempty" description:"Tags inherited by repositories"`
+	Remotes           []GitRemote       `yaml:"remotes,omitempty" description:"Remotes inherited by repositories"`
+	NoInherit         []string          `yaml:"noInherit,omitempty" jsonschema:"enum=scripts|tags|remotes" description:"Defaults of the workspace not inherited"`
+	Enabled           *bool             `yaml:"enabled,omitempty" description:"Execute commands in repositories of the group, true when not set"`
+	Archived          *bool             `yaml:"archived,omitempty" description:"Repositories of the group are retired and not executed in"`
+	ReadOnly          *bool             `yaml:"readOnly,omitempty" description:"Refuse git commands writing to repositories of the group"`
+
+	// InheritedEnvFiles are the dotenv files of the ancestors of a sub-group, outermost first, relative to
+	// workspace path or absolute. Set by Resolve.
+	InheritedEnvFiles []string `yaml:"-"`
 }
 
 type Repository struct {
-	Name              string      `yaml:"name"`
-	NameIgnoredInPath bool        `yaml:"nameIgnoredInPath"`
-	Path              string      `yaml:"path"`
-	Remotes           []GitRemote `yaml:"remotes"`
-	Scripts           []Script    `yaml:"scripts"`
-	Tags              []string    `yaml:"tags"`
+	Name              string            `yaml:"name" jsonschema:"required,pattern=^[A-Za-z0-9._-]+$" description:"Name of the repository"`
+	NameIgnoredInPath bool              `yaml:"nameIgnoredInPath,omitempty" description:"Don't append repository name to group path"`
+	Path              string            `yaml:"path,omitempty" description:"Path of the repository, relative to group path or absolute"`
+	Remotes           []GitRemote       `yaml:"remotes,omitempty" description:"Git remotes of the repository"`
+	Scripts           []Script          `yaml:"scripts,omitempty" description:"Scripts executed in the repository"`
+	Tags              []string          `yaml:"tags,omitempty" description:"Tags to filter repositories with"`
+	Env               map[string]string `yaml:"env,omitempty" description:"Environment variables of executions in the repository"`
+	EnvFile           string            `yaml:"envFile,omitempty" description:"Dotenv file of executions in the repository, relative to repository path"`
+	NoInherit         []string          `yaml:"noInherit,omitempty" jsonschema:"enum=scripts|tags|remotes" description:"Defaults of the workspace and group not inherited"`
+	Extends           []string          `yaml:"extends,omitempty" description:"Repository templates the repository is based on"`
+	DependsOn         []string          `yaml:"dependsOn,omitempty" description:"Repositories of the workspace built before the repository"`
+	Enabled           *bool             `yaml:"enabled,omitempty" description:"Execute commands in the repository, true when not set"`
+	Archived          *bool             `yaml:"archived,omitempty" description:"The repository is retired and not executed in"`
+	ReadOnly          *bool             `yaml:"readOnly,omitempty" description:"Refuse git commands writing to the repository"`
 }
 
 type GitRemote struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
+	Name string `yaml:"name" jsonschema:"required,pattern=^[A-Za-z0-9._-]+$" description:"Name of the remote"`
+	URL  string `yaml:"url,omitempty" jsonschema:"required" description:"URL of the remote, ${workspace.name}, ${group.name} and ${repository.name} are expanded"`
 }
 
 type Script struct {
-	Name    string   `yaml:"name"`
-	Default bool     `yaml:"default"`
-	Paths   []string `yaml:"paths"`
+	Name    string            `yaml:"name" jsonschema:"required,pattern=^[A-Za-z0-9._/-]+$" description:"Name of the script, a script definition or any command"`
+	Default bool              `yaml:"default,omitempty" description:"Execute the script when exec is called without script"`
+	Paths   []string          `yaml:"paths,omitempty" description:"Paths relative to repository path to execute the script in"`
+	Env     map[string]string `yaml:"env,omitempty" description:"Environment variables of executions of the script"`
+	EnvFile string            `yaml:"envFile,omitempty" description:"Dotenv file of executions of the script, relative to repository path"`
+	Timeout string            `yaml:"timeout,omitempty" jsonschema:"pattern=^([0-9]+(\\.[0-9]+)?(ns|us|ms|s|m|h))+$" description:"Time each execution of the script may take in the repository, such as 10m"`
+}
+
+// IsEnabled tells whether enabled is not set to false, in the repository or, once resolved, in its group
+// or workspace.
+func (r Repository) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+// IsArchived tells whether archived is set to true, in the repository or, once resolved, in its group or
+// workspace.
+func (r Repository) IsArchived() bool {
+	return r.Archived != nil && *r.Archived
+}
+
+// IsReadOnly tells whether readOnly is set to true, in the repository or, once resolved, in its group or
+// workspace.
+func (r Repository) IsReadOnly() bool {
+	return r.ReadOnly != nil && *r.ReadOnly
+}
+
+// IsReadOnly tells whether readOnly is set to true in the group or, once resolved, in its workspace.
+func (g Group) IsReadOnly() bool {
+	return g.ReadOnly != nil && *g.ReadOnly
+}
+
+// IsReadOnly tells whether readOnly is set to true in the workspace.
+func (w Workspace) IsReadOnly() bool {
+	return w.ReadOnly != nil && *w.ReadOnly
 }
 
 func Initialize() {
-	err := viper.Unmarshal(&HandyCiConfig)
+	var err error
+
+	// YAML, JSON and TOML files are decoded directly, so that they are validated against Schema and viper
+	// doesn't lower-case map keys, which breaks env variable names.
+	if configFile := viper.ConfigFileUsed(); isYAML(configFile) || isJSON(configFile) || isTOML(configFile) {
+		HandyCiConfig, err = Load(configFile)
+	} else {
+		err = viper.Unmarshal(&HandyCiConfig)
+
+		if err == nil && HandyCiConfig != nil {
+			err = HandyCiConfig.prepare()
+		}
+	}
+
 	if err != nil {
-		util.Printf("Unable to decode into config struct, %v", err)
+		util.Eprintf("Unable to load config, %v\n", err)
+	}
+
+	if HandyCiConfig == nil {
+		HandyCiConfig = &Config{}
 	}
+
+	if len(HandyCiConfig.AppliedOverlays) > 0 {
+		util.Eprintln("Using config overlays:", strings.Join(HandyCiConfig.AppliedOverlays, ", "))
+	}
+}
+
+func isYAML(file string) bool {
+	extension := strings.ToLower(filepath.Ext(file))
+
+	return extension == ".yaml" || extension == ".yml"
+}
+
+func isJSON(file string) bool {
+	return strings.ToLower(filepath.Ext(file)) == ".json"
+}
+
+func isTOML(file string) bool {
+	return strings.ToLower(filepath.Ext(file)) == ".toml"
+}
+
+// Load reads file, YAML, JSON or TOML, warns when it is outdated, and loads it like LoadDocument.
+func Load(file string) (*Config, error) {
+	document, err := readDocument(file)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := DocumentVersion(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if version < CurrentVersion {
+		// Migrations are written as YAML, other files are updated by hand.
+		if isYAML(file) {
+			util.Eprintf(
+				"Config version %d is outdated, current version is %d, run \"handy-ci config migrate\" to update %s\n",
+				version, CurrentVersion, file)
+		} else {
+			util.Eprintf("Config version %d is outdated, current version is %d, update %s\n", version, CurrentVersion, file)
+		}
+	}
+
+	return LoadDocument(document)
+}
+
+// LoadDocument migrates document in memory, validates it against Schema, decodes it, validates and resolves
+// the result. The config is returned together with validation errors, so that it can still be used.
+func LoadDocument(document *yaml.Node) (*Config, error) {
+	_, err := Migrate(document)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaErr := ValidateDocument(document)
+
+	config := &Config{}
+
+	err = document.Decode(config)
+	if err != nil {
+		if schemaErr != nil {
+			return nil, schemaErr
+		}
+
+		return nil, err
+	}
+
+	err = config.prepare()
+
+	if schemaErr != nil {
+		return config, schemaErr
+	}
+
+	return config, err
+}
+
+// prepare applies the overlays of the host, validates and resolves a decoded config.
+func (c *Config) prepare() error {
+	overlayErr := c.ApplyOverlays(CurrentHost(), Profiles())
+
+	validationErr := c.Validate()
+
+	err := c.Resolve()
+	if err != nil {
+		return err
+	}
+
+	if overlayErr != nil {
+		return overlayErr
+	}
+
+	return validationErr
 }
@@ -0,0 +1,302 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AddWorkspace appends workspace to the workspaces of document.
+func AddWorkspace(document *yaml.Node, workspace Workspace) error {
+	workspaces := sequenceValue(documentRoot(document), "workspaces")
+
+	if findNamed(workspaces, workspace.Name) != nil {
+		return fmt.Errorf("workspace %s already exists", workspace.Name)
+	}
+
+	return appendEncoded(workspaces, workspace)
+}
+
+// AddGroup appends group to the groups of workspace in document. A group named by a path, such as
+// spring-cloud/deployers, is appended to the sub-groups of its parent group.
+func AddGroup(document *yaml.Node, workspaceName string, group Group) error {
+	parent, err := findWorkspaceNode(document, workspaceName)
+	if err != nil {
+		return err
+	}
+
+	if index := strings.LastIndex(group.Name, "/"); index >= 0 {
+		parent, err = findGroupNode(document, workspaceName, group.Name[:index])
+		if err != nil {
+			return err
+		}
+
+		group.Name = group.Name[index+1:]
+	}
+
+	groups := sequenceValue(parent, "groups")
+
+	if findNamed(groups, group.Name) != nil {
+		return fmt.Errorf("group %s already exists in %s", group.Name, nodeName(parent))
+	}
+
+	return appendEncoded(groups, group)
+}
+
+// AddRepository appends repository to the repositories of group in document.
+func AddRepository(document *yaml.Node, workspaceName string, groupName string, repository Repository) error {
+	group, err := findGroupNode(document, workspaceName, groupName)
+	if err != nil {
+		return err
+	}
+
+	repositories := sequenceValue(group, "repositories")
+
+	if findNamed(repositories, repository.Name) != nil {
+		return fmt.Errorf("repository %s already exists in group %s", repository.Name, nodeName(group))
+	}
+
+	return appendEncoded(repositories, repository)
+}
+
+// RemoveRepository removes repository from group in document. Workspace and group can be empty when the
+// repository name is unique.
+func RemoveRepository(document *yaml.Node, workspaceName string, groupName string, repositoryName string) error {
+	type match struct {
+		repositories *yaml.Node
+		index        int
+	}
+
+	var matches []match
+
+	for _, workspace := range sequenceItems(mappingValue(documentRoot(document), "workspaces")) {
+		if workspaceName != "" && nodeName(workspace) != workspaceName {
+			continue
+		}
+
+		for groupPath, group := range groupNodes(workspace, "") {
+			if groupName != "" && groupPath != groupName {
+				continue
+			}
+
+			repositories := mappingValue(group, "repositories")
+
+			for i, repository := range sequenceItems(repositories) {
+				if nodeName(repository) == repositoryName {
+					matches = append(matches, match{repositories, i})
+				}
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		return fmt.Errorf("repository %s not found", repositoryName)
+	}
+
+	if len(matches) > 1 {
+		return fmt.Errorf("repository %s found in more than one group, specify workspace and group", repositoryName)
+	}
+
+	repositories := matches[0].repositories
+	repositories.Content = append(repositories.Content[:matches[0].index], repositories.Content[matches[0].index+1:]...)
+
+	return nil
+}
+
+// RepositoryNameFromURL returns the repository name of a git URL, the last path element without .git.
+func RepositoryNameFromURL(url string) string {
+	name := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git"), "/")
+
+	if index := strings.LastIndexAny(name, "/:"); index >= 0 {
+		name = name[index+1:]
+	}
+
+	return name
+}
+
+// Set sets the value at a dot-delimited key path of document, such as workspaces.keepnative.path. Items
+// of lists are addressed by name or index, missing keys are created. Value is parsed as YAML, so that
+// booleans, numbers and flow lists keep their type.
+func Set(document *yaml.Node, keyPath string, value string) error {
+	var valueDocument yaml.Node
+
+	err := yaml.Unmarshal([]byte(value), &valueDocument)
+	if err != nil {
+		return err
+	}
+
+	valueNode := scalarNode(value)
+	if valueDocument.Kind == yaml.DocumentNode && len(valueDocument.Content) > 0 {
+		valueNode = valueDocument.Content[0]
+	}
+
+	keys := strings.Split(keyPath, ".")
+	current := documentRoot(document)
+
+	for i, key := range keys {
+		last := i == len(keys)-1
+
+		switch current.Kind {
+		case yaml.MappingNode:
+			if last {
+				setMappingValue(current, key, valueNode)
+				return nil
+			}
+
+			next := mappingValue(current, key)
+			if next == nil {
+				next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				setMappingValue(current, key, next)
+			}
+
+			current = next
+		case yaml.SequenceNode:
+			index := indexOf(current, key)
+			if index < 0 {
+				return fmt.Errorf("%s not found in %s", key, strings.Join(keys[:i], "."))
+			}
+
+			if last {
+				current.Content[index] = valueNode
+				return nil
+			}
+
+			current = current.Content[index]
+		default:
+			return fmt.Errorf("%s is not a map or list", strings.Join(keys[:i], "."))
+		}
+	}
+
+	return nil
+}
+
+func findWorkspaceNode(document *yaml.Node, workspaceName string) (*yaml.Node, error) {
+	workspaces := sequenceItems(mappingValue(documentRoot(document), "workspaces"))
+
+	if workspaceName == "" && len(workspaces) == 1 {
+		return workspaces[0], nil
+	}
+
+	if workspaceName == "" {
+		return nil, fmt.Errorf("workspace is required")
+	}
+
+	for _, workspace := range workspaces {
+		if nodeName(workspace) == workspaceName {
+			return workspace, nil
+		}
+	}
+
+	return nil, fmt.Errorf("workspace %s not found", workspaceName)
+}
+
+func findGroupNode(document *yaml.Node, workspaceName string, groupName string) (*yaml.Node, error) {
+	workspace, err := findWorkspaceNode(document, workspaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := sequenceItems(mappingValue(workspace, "groups"))
+
+	if groupName == "" && len(groups) == 1 {
+		return groups[0], nil
+	}
+
+	if groupName == "" {
+		return nil, fmt.Errorf("group is required")
+	}
+
+	if group, found := groupNodes(workspace, "")[groupName]; found {
+		return group, nil
+	}
+
+	return nil, fmt.Errorf("group %s not found in workspace %s", groupName, nodeName(workspace))
+}
+
+// groupNodes returns the groups of parent and their sub-groups, recursively, by path.
+func groupNodes(parent *yaml.Node, prefix string) map[string]*yaml.Node {
+	groups := map[string]*yaml.Node{}
+
+	for _, group := range sequenceItems(mappingValue(parent, "groups")) {
+		groupPath := prefix + nodeName(group)
+
+		groups[groupPath] = group
+
+		for subGroupPath, subGroup := range groupNodes(group, groupPath+"/") {
+			groups[subGroupPath] = subGroup
+		}
+	}
+
+	return groups
+}
+
+// sequenceValue returns the list value of key in mapping, creating an empty list when not present.
+func sequenceValue(mapping *yaml.Node, key string) *yaml.Node {
+	sequence := mappingValue(mapping, key)
+
+	if sequence == nil || sequence.Kind != yaml.SequenceNode {
+		sequence = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(mapping, key, sequence)
+	}
+
+	return sequence
+}
+
+func sequenceItems(sequence *yaml.Node) []*yaml.Node {
+	if sequence == nil || sequence.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	return sequence.Content
+}
+
+func findNamed(sequence *yaml.Node, name string) *yaml.Node {
+	for _, item := range sequenceItems(sequence) {
+		if nodeName(item) == name {
+			return item
+		}
+	}
+
+	return nil
+}
+
+func nodeName(node *yaml.Node) string {
+	if name := mappingValue(node, "name"); name != nil {
+		return name.Value
+	}
+
+	return ""
+}
+
+// indexOf returns the index of the item of sequence named key, or at index key.
+func indexOf(sequence *yaml.Node, key string) int {
+	for i, item := range sequence.Content {
+		if nodeName(item) == key {
+			return i
+		}
+	}
+
+	index, err := strconv.Atoi(key)
+	if err == nil && index >= 0 && index < len(sequence.Content) {
+		return index
+	}
+
+	return -1
+}
+
+func appendEncoded(sequence *yaml.Node, value interface{}) error {
+	var node yaml.Node
+
+	err := node.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	// An empty list written as [] would otherwise render the new item in flow style.
+	sequence.Style &^= yaml.FlowStyle
+	sequence.Content = append(sequence.Content, &node)
+
+	return nil
+}
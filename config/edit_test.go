@@ -0,0 +1,132 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func parseDocument(t *testing.T, content string) *yaml.Node {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		t.Fatal(err)
+	}
+	return &document
+}
+
+func encodeDocument(t *testing.T, document *yaml.Node) string {
+	content, err := EncodeDocument(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestEdit_AddAndRemoveKeepComments(t *testing.T) {
+	document := parseDocument(t, `version: 2
+workspaces:
+  # main workspace
+  - name: keepnative
+    path: /coding/keepnative
+    groups:
+      - name: next
+        repositories: []
+`)
+
+	if err := AddGroup(document, "keepnative", Group{Name: "spring-cloud"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := AddRepository(document, "keepnative", "next", Repository{Name: "java"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := AddRepository(document, "keepnative", "next", Repository{Name: "java"}); err == nil {
+		t.Fatalf("expected error for duplicate repository")
+	}
+	if err := AddRepository(document, "", "", Repository{Name: "soupe"}); err == nil {
+		t.Fatalf("expected error when group is ambiguous")
+	}
+
+	output := encodeDocument(t, document)
+	if !strings.Contains(output, "# main workspace") {
+		t.Fatalf("expected comment to be kept:\n%s", output)
+	}
+	if !strings.Contains(output, "repositories:\n          - name: java") {
+		t.Fatalf("expected repository in block style:\n%s", output)
+	}
+	if !strings.Contains(output, "- name: spring-cloud") {
+		t.Fatalf("expected group added:\n%s", output)
+	}
+
+	if err := RemoveRepository(document, "", "", "java"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RemoveRepository(document, "", "", "java"); err == nil {
+		t.Fatalf("expected error for removed repository")
+	}
+}
+
+func TestEdit_Set(t *testing.T) {
+	document := parseDocument(t, `workspaces:
+  - name: keepnative
+    path: /coding/keepnative # old
+`)
+
+	if err := Set(document, "workspaces.keepnative.path", "/home/keepnative"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Set(document, "workspaces.0.tags", "[java, next]"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Set(document, "workspaces.missing.path", "/x"); err == nil {
+		t.Fatalf("expected error for missing workspace")
+	}
+
+	var config Config
+	if err := document.Decode(&config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Workspaces[0].Path != "/home/keepnative" || len(config.Workspaces[0].Tags) != 2 {
+		t.Fatalf("unexpected config: %#v", config.Workspaces[0])
+	}
+}
+
+func TestRepositoryNameFromURL(t *testing.T) {
+	for url, expected := range map[string]string{
+		"git@gitlab.com:keepnative/java.git":         "java",
+		"https://github.com/carrchang/handy-ci.git/": "handy-ci",
+		"git@host:soupe": "soupe",
+		"/src/demo/.git": "demo",
+	} {
+		if got := RepositoryNameFromURL(url); got != expected {
+			t.Fatalf("expected %s for %s, got %s", expected, url, got)
+		}
+	}
+}
+
+func TestEdit_NestedGroups(t *testing.T) {
+	document := parseDocument(t, `workspaces:
+  - name: keepnative
+    groups:
+      - name: spring-cloud
+`)
+
+	if err := AddGroup(document, "", Group{Name: "spring-cloud/deployers"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := AddRepository(document, "", "spring-cloud/deployers", Repository{Name: "kubernetes"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := AddGroup(document, "", Group{Name: "missing/deployers"}); err == nil {
+		t.Fatalf("expected error for undefined parent group")
+	}
+
+	output := encodeDocument(t, document)
+	if !strings.Contains(output, "groups:\n          - name: deployers\n            repositories:\n              - name: kubernetes") {
+		t.Fatalf("expected nested group with repository:\n%s", output)
+	}
+
+	if err := RemoveRepository(document, "", "spring-cloud/deployers", "kubernetes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
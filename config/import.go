@@ -0,0 +1,493 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const ImportFormatRepo = "repo"
+const ImportFormatMr = "mr"
+const ImportFormatGita = "gita"
+const ImportFormatMeta = "meta"
+const ImportFormatVSCode = "vscode"
+
+// ImportFormats are the formats of other multi-repository tools Import converts.
+var ImportFormats = []string{ImportFormatRepo, ImportFormatMr, ImportFormatGita, ImportFormatMeta, ImportFormatVSCode}
+
+// Import converts content, in one of ImportFormats, into a workspace. Relative paths of content are
+// resolved against dir, the workspace path is the common directory of all repositories. Repositories are
+// placed in groups, nested after the directories they are in. Items that can't be expressed in a workspace
+// are returned as problems.
+func Import(format string, content []byte, dir string) (Workspace, []string, error) {
+	importer := &workspaceImporter{dir: filepath.ToSlash(dir)}
+
+	var err error
+
+	switch format {
+	case ImportFormatRepo:
+		err = importer.importRepoManifest(content)
+	case ImportFormatMr:
+		err = importer.importMrConfig(content)
+	case ImportFormatGita:
+		err = importer.importGitaRepos(content)
+	case ImportFormatMeta:
+		err = importer.importMeta(content)
+	case ImportFormatVSCode:
+		err = importer.importVSCodeWorkspace(content)
+	default:
+		err = fmt.Errorf("unknown import format %s, supported formats are %s", format, strings.Join(ImportFormats, ", "))
+	}
+
+	if err != nil {
+		return Workspace{}, nil, err
+	}
+
+	return importer.workspace(), importer.problems, nil
+}
+
+type workspaceImporter struct {
+	dir          string
+	repositories []importedRepository
+	problems     []string
+}
+
+type importedRepository struct {
+	path       string
+	repository Repository
+}
+
+func (i *workspaceImporter) problem(format string, a ...interface{}) {
+	i.problems = append(i.problems, fmt.Sprintf(format, a...))
+}
+
+// add adds a repository at repositoryPath, relative to dir or absolute, with origin remote url if not empty.
+func (i *workspaceImporter) add(repositoryPath string, name string, url string) {
+	repositoryPath = path.Clean(filepath.ToSlash(repositoryPath))
+
+	if !path.IsAbs(repositoryPath) {
+		repositoryPath = path.Join(i.dir, repositoryPath)
+	}
+
+	repository := Repository{Name: importedName(name)}
+	if repository.Name == "" {
+		repository.Name = importedName(path.Base(repositoryPath))
+	}
+
+	if url != "" {
+		repository.Remotes = []GitRemote{{Name: "origin", URL: url}}
+	}
+
+	i.repositories = append(i.repositories, importedRepository{repositoryPath, repository})
+}
+
+// workspace places imported repositories in groups after their directory relative to the workspace path,
+// which is the common directory of all repositories.
+func (i *workspaceImporter) workspace() Workspace {
+	root := i.dir
+	if len(i.repositories) > 0 {
+		root = path.Dir(i.repositories[0].path)
+	}
+
+	for _, imported := range i.repositories {
+		for root != "/" && root != "." && !strings.HasPrefix(imported.path+"/", strings.TrimSuffix(root, "/")+"/") {
+			root = path.Dir(root)
+		}
+	}
+
+	workspace := Workspace{Name: importedName(path.Base(root)), Path: root}
+
+	for _, imported := range i.repositories {
+		relative := strings.TrimPrefix(strings.TrimPrefix(imported.path, root), "/")
+
+		repository := imported.repository
+		if path.Base(relative) != repository.Name {
+			repository.Path = path.Base(relative)
+		}
+
+		groups := &workspace.Groups
+		directory := path.Dir(relative)
+
+		if directory == "." {
+			group := importedGroup(groups, workspace.Name)
+			group.NameIgnoredInPath = true
+			group.Repositories = append(group.Repositories, repository)
+
+			continue
+		}
+
+		var group *Group
+		for _, segment := range strings.Split(directory, "/") {
+			group = importedGroup(groups, importedName(segment))
+
+			if group.Name != segment {
+				group.Path = segment
+			}
+
+			groups = &group.Groups
+		}
+
+		group.Repositories = append(group.Repositories, repository)
+	}
+
+	return workspace
+}
+
+// importedGroup returns the group named name in groups, appending it when not found.
+func importedGroup(groups *[]Group, name string) *Group {
+	for j := range *groups {
+		if (*groups)[j].Name == name {
+			return &(*groups)[j]
+		}
+	}
+
+	*groups = append(*groups, Group{Name: name})
+
+	return &(*groups)[len(*groups)-1]
+}
+
+var invalidNameCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// importedName replaces the characters not allowed in names with -.
+func importedName(name string) string {
+	return strings.Trim(invalidNameCharacters.ReplaceAllString(name, "-"), "-")
+}
+
+type repoManifest struct {
+	Remotes []struct {
+		Name  string `xml:"name,attr"`
+		Fetch string `xml:"fetch,attr"`
+	} `xml:"remote"`
+	Default struct {
+		Remote string `xml:"remote,attr"`
+	} `xml:"default"`
+	Projects []struct {
+		Name     string `xml:"name,attr"`
+		Path     string `xml:"path,attr"`
+		Remote   string `xml:"remote,attr"`
+		Revision string `xml:"revision,attr"`
+		Groups   string `xml:"groups,attr"`
+		Inner    []struct {
+			XMLName xml.Name
+		} `xml:",any"`
+	} `xml:"project"`
+	Others []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+// importRepoManifest imports the projects of a Google repo manifest, project groups become tags.
+func (i *workspaceImporter) importRepoManifest(content []byte) error {
+	var manifest repoManifest
+
+	err := xml.Unmarshal(content, &manifest)
+	if err != nil {
+		return err
+	}
+
+	fetches := map[string]string{}
+	for _, remote := range manifest.Remotes {
+		fetches[remote.Name] = remote.Fetch
+	}
+
+	for _, other := range manifest.Others {
+		i.problem("manifest element <%s> not imported", other.XMLName.Local)
+	}
+
+	for _, project := range manifest.Projects {
+		projectPath := project.Path
+		if projectPath == "" {
+			projectPath = project.Name
+		}
+
+		remoteName := project.Remote
+		if remoteName == "" {
+			remoteName = manifest.Default.Remote
+		}
+
+		var url string
+
+		fetch, found := fetches[remoteName]
+
+		switch {
+		case !found:
+			i.problem("remote %s of project %s not defined", remoteName, project.Name)
+		case !strings.Contains(fetch, ":"):
+			i.problem("relative fetch %s of remote %s for project %s can't be resolved", fetch, remoteName, project.Name)
+		default:
+			url = strings.TrimSuffix(fetch, "/") + "/" + project.Name
+		}
+
+		if project.Revision != "" {
+			i.problem("revision %s of project %s not imported", project.Revision, project.Name)
+		}
+
+		for _, inner := range project.Inner {
+			i.problem("element <%s> of project %s not imported", inner.XMLName.Local, project.Name)
+		}
+
+		i.add(projectPath, "", url)
+
+		for _, tag := range strings.FieldsFunc(project.Groups, func(r rune) bool { return r == ',' || r == ' ' }) {
+			repository := &i.repositories[len(i.repositories)-1].repository
+			repository.Tags = append(repository.Tags, tag)
+		}
+	}
+
+	return nil
+}
+
+var mrCheckout = regexp.MustCompile(`^git clone\s+(?:'([^']+)'|"([^"]+)"|(\S+))(?:\s+(?:'([^']+)'|"([^"]+)"|(\S+)))?\s*$`)
+
+// importMrConfig imports the sections of a .mrconfig, the url is taken from git clone checkout commands.
+func (i *workspaceImporter) importMrConfig(content []byte) error {
+	var section string
+	var url string
+	var sections int
+
+	flush := func() {
+		if section == "" || section == "DEFAULT" {
+			return
+		}
+
+		if url == "" {
+			i.problem("section %s has no git clone checkout, remote not imported", section)
+		}
+
+		i.add(section, "", url)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			flush()
+
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			url = ""
+			sections++
+
+			if section == "DEFAULT" {
+				i.problem("section DEFAULT not imported")
+			}
+
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if section == "" || section == "DEFAULT" {
+			continue
+		}
+
+		if key == "checkout" {
+			if match := mrCheckout.FindStringSubmatch(value); match != nil {
+				url = match[1] + match[2] + match[3]
+			}
+
+			continue
+		}
+
+		i.problem("%s = %s of section %s not imported", key, value, section)
+	}
+
+	flush()
+
+	if sections == 0 {
+		return fmt.Errorf("no sections found in mr config")
+	}
+
+	return scanner.Err()
+}
+
+// importGitaRepos imports the repos.csv of gita, in path,name[,type,flags] lines.
+func (i *workspaceImporter) importGitaRepos(content []byte) error {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		var name string
+		if len(record) > 1 {
+			name = strings.TrimSpace(record[1])
+		}
+
+		for _, field := range record[2:] {
+			if field = strings.TrimSpace(field); field != "" {
+				i.problem("%s of repository %s not imported", field, record[0])
+			}
+		}
+
+		i.add(strings.TrimSpace(record[0]), name, "")
+	}
+
+	if len(i.repositories) > 0 {
+		i.problem("gita doesn't record remotes, add origin remotes to clone repositories")
+	}
+
+	return nil
+}
+
+// importMeta imports the projects of a meta .meta file, mapping directories to git URLs.
+func (i *workspaceImporter) importMeta(content []byte) error {
+	var meta map[string]json.RawMessage
+
+	err := json.Unmarshal(content, &meta)
+	if err != nil {
+		return err
+	}
+
+	var projects map[string]string
+
+	err = json.Unmarshal(meta["projects"], &projects)
+	if err != nil {
+		return fmt.Errorf("projects of meta file, %v", err)
+	}
+
+	for _, key := range sortedKeys(meta) {
+		if key != "projects" {
+			i.problem("%s of meta file not imported", key)
+		}
+	}
+
+	var directories []string
+	for directory := range projects {
+		directories = append(directories, directory)
+	}
+
+	sort.Strings(directories)
+
+	for _, directory := range directories {
+		i.add(directory, "", projects[directory])
+	}
+
+	return nil
+}
+
+// importVSCodeWorkspace imports the folders of a VS Code .code-workspace file.
+func (i *workspaceImporter) importVSCodeWorkspace(content []byte) error {
+	var codeWorkspace map[string]json.RawMessage
+
+	err := json.Unmarshal(stripJSONComments(content), &codeWorkspace)
+	if err != nil {
+		return err
+	}
+
+	var folders []struct {
+		Path string `json:"path"`
+		Name string `json:"name"`
+		URI  string `json:"uri"`
+	}
+
+	err = json.Unmarshal(codeWorkspace["folders"], &folders)
+	if err != nil {
+		return fmt.Errorf("folders of code workspace, %v", err)
+	}
+
+	for _, key := range sortedKeys(codeWorkspace) {
+		if key != "folders" {
+			i.problem("%s of code workspace not imported", key)
+		}
+	}
+
+	for _, folder := range folders {
+		if folder.Path == "" {
+			i.problem("folder %s%s without path not imported", folder.Name, folder.URI)
+			continue
+		}
+
+		i.add(folder.Path, folder.Name, "")
+	}
+
+	if len(i.repositories) > 0 {
+		i.problem("code workspaces don't record remotes, add origin remotes to clone repositories")
+	}
+
+	return nil
+}
+
+func sortedKeys(values map[string]json.RawMessage) []string {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+var trailingCommas = regexp.MustCompile(`,(\s*[}\]])`)
+
+// stripJSONComments removes the comments and trailing commas VS Code allows in its JSON files.
+func stripJSONComments(content []byte) []byte {
+	var stripped bytes.Buffer
+
+	var inString, escaped bool
+
+	for j := 0; j < len(content); j++ {
+		c := content[j]
+
+		if inString {
+			stripped.WriteByte(c)
+
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+		case c == '/' && j+1 < len(content) && content[j+1] == '/':
+			for j < len(content) && content[j] != '\n' {
+				j++
+			}
+		case c == '/' && j+1 < len(content) && content[j+1] == '*':
+			end := bytes.Index(content[j+2:], []byte("*/"))
+			if end < 0 {
+				j = len(content)
+			} else {
+				j += end + 3
+			}
+
+			continue
+		}
+
+		if j < len(content) {
+			stripped.WriteByte(content[j])
+		}
+	}
+
+	return trailingCommas.ReplaceAll(stripped.Bytes(), []byte("$1"))
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImport_RepoManifest(t *testing.T) {
+	manifest := `<manifest>
+  <remote name="github" fetch="https://github.com/spring-cloud" />
+  <default remote="github" revision="main" />
+  <project name="spring-cloud-deployer" path="spring-cloud/deployer" groups="java" />
+  <project name="spring-cloud-deployer-kubernetes" path="spring-cloud/deployers/kubernetes" revision="v2" />
+  <notice>text</notice>
+</manifest>`
+
+	workspace, problems, err := Import(ImportFormatRepo, []byte(manifest), "/coding/keepnative")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if workspace.Name != "spring-cloud" || workspace.Path != "/coding/keepnative/spring-cloud" {
+		t.Fatalf("unexpected workspace: %s %s", workspace.Name, workspace.Path)
+	}
+
+	deployer := workspace.Groups[0].Repositories[0]
+	if deployer.Name != "deployer" || !reflect.DeepEqual(deployer.Tags, []string{"java"}) ||
+		deployer.Remotes[0].URL != "https://github.com/spring-cloud/spring-cloud-deployer" {
+		t.Fatalf("unexpected repository: %#v", deployer)
+	}
+
+	if workspace.Groups[1].Name != "deployers" || workspace.Groups[1].Repositories[0].Name != "kubernetes" {
+		t.Fatalf("unexpected groups: %#v", workspace.Groups)
+	}
+
+	if len(problems) != 2 || !strings.Contains(problems[0], "notice") || !strings.Contains(problems[1], "revision v2") {
+		t.Fatalf("unexpected problems: %#v", problems)
+	}
+}
+
+func TestImport_MrConfig(t *testing.T) {
+	mrconfig := `[src/handy-ci]
+checkout = git clone 'git@github.com:carrchang/handy-ci.git' 'handy-ci'
+build = make
+
+[docs]
+checkout = svn co https://example.com/docs
+`
+
+	workspace, problems, err := Import(ImportFormatMr, []byte(mrconfig), "/coding")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if workspace.Path != "/coding" || len(workspace.Groups) != 2 {
+		t.Fatalf("unexpected workspace: %#v", workspace)
+	}
+
+	handyCi := workspace.Groups[0].Repositories[0]
+	if workspace.Groups[0].Name != "src" || handyCi.Remotes[0].URL != "git@github.com:carrchang/handy-ci.git" {
+		t.Fatalf("unexpected repository: %#v", handyCi)
+	}
+
+	if !workspace.Groups[1].NameIgnoredInPath || workspace.Groups[1].Repositories[0].Name != "docs" {
+		t.Fatalf("expected docs in workspace path: %#v", workspace.Groups[1])
+	}
+
+	if len(problems) != 2 {
+		t.Fatalf("unexpected problems: %#v", problems)
+	}
+}
+
+func TestImport_MetaAndVSCode(t *testing.T) {
+	meta := `{"projects": {"api": "git@github.com:x/api.git", "libs/core": "git@github.com:x/core.git"}}`
+
+	workspace, _, err := Import(ImportFormatMeta, []byte(meta), "/coding/x")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if workspace.Groups[0].Repositories[0].Name != "api" || workspace.Groups[1].Name != "libs" {
+		t.Fatalf("unexpected workspace: %#v", workspace)
+	}
+
+	codeWorkspace := `{
+  // folders
+  "folders": [
+    {"path": "api", "name": "API service"},
+    {"path": "web"}, /* trailing comma */
+  ],
+}`
+
+	workspace, _, err = Import(ImportFormatVSCode, []byte(codeWorkspace), "/coding/x")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	api := workspace.Groups[0].Repositories[0]
+	if api.Name != "API-service" || api.Path != "api" {
+		t.Fatalf("unexpected repository: %#v", api)
+	}
+}
+
+func TestImport_UnknownFormat(t *testing.T) {
+	if _, _, err := Import("unknown", nil, "/"); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
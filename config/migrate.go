@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the version of the configuration schema read by this release.
+const CurrentVersion = 2
+
+// Migration rewrites a configuration document of version From to version To.
+type Migration struct {
+	From        int
+	To          int
+	Description string
+	Apply       func(root *yaml.Node) error
+}
+
+// Migrations is the registry of migrations, applied in order to bring a document to CurrentVersion.
+var Migrations = []Migration{
+	{
+		From:        1,
+		To:          2,
+		Description: "Rename root of workspaces to path",
+		Apply:       renameWorkspaceRoot,
+	},
+}
+
+// DocumentVersion returns the schema version of document, a document without version is version 1.
+func DocumentVersion(document *yaml.Node) (int, error) {
+	versionNode := mappingValue(documentRoot(document), "version")
+	if versionNode == nil {
+		return 1, nil
+	}
+
+	version, err := strconv.Atoi(versionNode.Value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid config version %s", versionNode.Value)
+	}
+
+	return version, nil
+}
+
+// Migrate applies migrations to document until it reaches CurrentVersion, and returns the applied
+// migrations. Comments and key order of document are kept.
+func Migrate(document *yaml.Node) ([]Migration, error) {
+	version, err := DocumentVersion(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if version > CurrentVersion {
+		return nil, fmt.Errorf(
+			"config version %d is newer than version %d supported, upgrade handy-ci", version, CurrentVersion)
+	}
+
+	var applied []Migration
+
+	for version < CurrentVersion {
+		migration, found := findMigration(version)
+		if !found {
+			return applied, fmt.Errorf("no migration from config version %d", version)
+		}
+
+		root := documentRoot(document)
+
+		err = migration.Apply(root)
+		if err != nil {
+			return applied, err
+		}
+
+		setVersion(root, migration.To)
+
+		applied = append(applied, migration)
+		version = migration.To
+	}
+
+	return applied, nil
+}
+
+func findMigration(from int) (Migration, bool) {
+	for _, migration := range Migrations {
+		if migration.From == from {
+			return migration, true
+		}
+	}
+
+	return Migration{}, false
+}
+
+// setVersion sets version of root, a new version key is placed in front of other keys.
+func setVersion(root *yaml.Node, version int) {
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+
+	if mappingValue(root, "version") != nil {
+		setMappingValue(root, "version", value)
+		return
+	}
+
+	key := scalarNode("version")
+
+	// Keep the leading comment of the document in front of it.
+	if len(root.Content) > 0 {
+		key.HeadComment = root.Content[0].HeadComment
+		root.Content[0].HeadComment = ""
+	}
+
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+func renameWorkspaceRoot(root *yaml.Node) error {
+	workspaces := mappingValue(root, "workspaces")
+	if workspaces == nil {
+		return nil
+	}
+
+	for _, workspace := range workspaces.Content {
+		if workspace.Kind != yaml.MappingNode || mappingValue(workspace, "path") != nil {
+			continue
+		}
+
+		for i := 0; i+1 < len(workspace.Content); i += 2 {
+			if workspace.Content[i].Value == "root" {
+				workspace.Content[i].Value = "path"
+			}
+		}
+	}
+
+	return nil
+}
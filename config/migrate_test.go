@@ -0,0 +1,67 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrate_RenamesWorkspaceRootAndSetsVersion(t *testing.T) {
+	var document yaml.Node
+	content := `# workspaces
+workspaces:
+  - name: home # my home
+    root: /Users
+  - name: keepnative
+    path: /coding/keepnative
+`
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := Migrate(&document)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 1 || migrations[0].To != CurrentVersion {
+		t.Fatalf("unexpected migrations: %#v", migrations)
+	}
+
+	output, err := EncodeDocument(&document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# workspaces
+version: 2
+workspaces:
+  - name: home # my home
+    path: /Users
+`
+	if !strings.HasPrefix(string(output), expected) {
+		t.Fatalf("unexpected migrated document:\n%s", output)
+	}
+
+	var config Config
+	if err := document.Decode(&config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Version != CurrentVersion || config.Workspaces[0].Path != "/Users" {
+		t.Fatalf("unexpected decoded config: %#v", config)
+	}
+
+	migrations, err = Migrate(&document)
+	if err != nil || len(migrations) != 0 {
+		t.Fatalf("expected no migration for current document, got %#v %v", migrations, err)
+	}
+}
+
+func TestMigrate_NewerVersion(t *testing.T) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte("version: 99\n"), &document); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(&document); err == nil {
+		t.Fatalf("expected error for newer config version")
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ReadDocument reads file as a YAML node tree, keeping comments and key order.
+func ReadDocument(file string) (*yaml.Node, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+
+	err = yaml.Unmarshal(content, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	if document.Kind == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	return &document, nil
+}
+
+// readDocument reads file like ReadDocument, JSON being YAML already, a TOML file is converted to a YAML
+// node tree, without its comments.
+func readDocument(file string) (*yaml.Node, error) {
+	if !isTOML(file) {
+		return ReadDocument(file)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	value := map[string]interface{}{}
+
+	err = toml.Unmarshal(content, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+
+	err = root.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&root}}, nil
+}
+
+// NewDocument returns a document for a new configuration of the current version.
+func NewDocument() *yaml.Node {
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setVersion(root, CurrentVersion)
+
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+}
+
+// EncodeDocument encodes document with the indentation used in configuration examples.
+func EncodeDocument(document *yaml.Node) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	err := encoder.Encode(document)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+
+	return buffer.Bytes(), err
+}
+
+// WriteDocument writes document to file.
+func WriteDocument(file string, document *yaml.Node) error {
+	content, err := EncodeDocument(document)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, content, 0644)
+}
+
+// documentRoot returns the top level mapping of document.
+func documentRoot(document *yaml.Node) *yaml.Node {
+	if document.Kind == yaml.DocumentNode && len(document.Content) > 0 {
+		return document.Content[0]
+	}
+
+	return document
+}
+
+// mappingValue returns the value of key in mapping, or nil when key not present.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// setMappingValue replaces the value of key in mapping, or appends key when not present.
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+
+	mapping.Content = append(mapping.Content, scalarNode(key), value)
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
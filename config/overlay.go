@@ -0,0 +1,284 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/carrchang/handy-ci/util"
+)
+
+// EnvProfile lists, comma separated, the overlays applied in addition to the overlays matching the host.
+const EnvProfile = "HANDY_CI_PROFILE"
+
+// Host describes the machine overlays are matched against.
+type Host struct {
+	OS       string
+	Hostname string
+	Getenv   func(string) string
+}
+
+// CurrentHost returns the machine Handy CI runs on.
+func CurrentHost() Host {
+	hostname, _ := os.Hostname()
+
+	return Host{OS: runtime.GOOS, Hostname: hostname, Getenv: os.Getenv}
+}
+
+// Profiles returns the overlay names listed in HANDY_CI_PROFILE.
+func Profiles() []string {
+	var profiles []string
+
+	for _, profile := range strings.Split(os.Getenv(EnvProfile), ",") {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	return profiles
+}
+
+// Matches tells whether all conditions of overlay hold on host. An overlay without conditions only applies
+// when it is selected by profile.
+func (o Overlay) Matches(host Host) bool {
+	when := o.When
+
+	if when.OS == "" && when.Hostname == "" && len(when.Env) == 0 {
+		return false
+	}
+
+	if when.OS != "" && !strings.EqualFold(when.OS, host.OS) {
+		return false
+	}
+
+	if when.Hostname != "" {
+		matched, err := path.Match(strings.ToLower(when.Hostname), strings.ToLower(host.Hostname))
+		if err != nil || !matched {
+			return false
+		}
+	}
+
+	for name, pattern := range when.Env {
+		value := host.Getenv(name)
+
+		// An empty pattern only requires the variable to be set.
+		if pattern == "" {
+			if value == "" {
+				return false
+			}
+
+			continue
+		}
+
+		matched, err := path.Match(pattern, value)
+		if err != nil || !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ApplyOverlays merges the overlays matching host or named in profiles on top of the config, in the order
+// they are declared. Overlays are removed from the config and the names of the applied ones are recorded
+// in AppliedOverlays.
+func (c *Config) ApplyOverlays(host Host, profiles []string) error {
+	for _, profile := range profiles {
+		if _, found := c.findOverlay(profile); !found {
+			return fmt.Errorf("profile %s selected by %s not defined in overlays", profile, EnvProfile)
+		}
+	}
+
+	for _, overlay := range c.Overlays {
+		if !overlay.Matches(host) && !util.ContainArgs(profiles, overlay.Name) {
+			continue
+		}
+
+		c.ScriptDefinitions = mergeScriptDefinitions(c.ScriptDefinitions, overlay.ScriptDefinitions)
+
+		for _, template := range overlay.RepositoryTemplates {
+			if i := indexOfRepository(c.RepositoryTemplates, template.Name); i >= 0 {
+				c.RepositoryTemplates[i] = mergeRepository(c.RepositoryTemplates[i], template)
+			} else {
+				c.RepositoryTemplates = append(c.RepositoryTemplates, template)
+			}
+		}
+
+		for _, workspace := range overlay.Workspaces {
+			if i := indexOfWorkspace(c.Workspaces, workspace.Name); i >= 0 {
+				c.Workspaces[i] = mergeWorkspace(c.Workspaces[i], workspace)
+			} else {
+				c.Workspaces = append(c.Workspaces, workspace)
+			}
+		}
+
+		c.AppliedOverlays = append(c.AppliedOverlays, overlay.Name)
+	}
+
+	c.Overlays = nil
+
+	return nil
+}
+
+func (c *Config) findOverlay(name string) (Overlay, bool) {
+	for _, overlay := range c.Overlays {
+		if overlay.Name == name {
+			return overlay, true
+		}
+	}
+
+	return Overlay{}, false
+}
+
+// mergeScriptDefinitions replaces the script definitions of base with the ones of override with the same
+// name, and appends the others.
+func mergeScriptDefinitions(base []ScriptDefinition, override []ScriptDefinition) []ScriptDefinition {
+	merged := append([]ScriptDefinition{}, base...)
+
+	for _, scriptDefinition := range override {
+		var replaced bool
+
+		for i := range merged {
+			if merged[i].Name == scriptDefinition.Name {
+				merged[i] = scriptDefinition
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			merged = append(merged, scriptDefinition)
+		}
+	}
+
+	return merged
+}
+
+// mergeWorkspace deep merges override into base like mergeRepository, groups are merged by name.
+func mergeWorkspace(base Workspace, override Workspace) Workspace {
+	merged := base
+
+	if override.Path != "" {
+		merged.Path = override.Path
+	}
+
+	if override.EnvFile != "" {
+		merged.EnvFile = override.EnvFile
+	}
+
+	merged.Env = mergeEnv(base.Env, override.Env)
+	merged.Scripts = deepMergeScripts(base.Scripts, override.Scripts)
+	merged.Tags = mergeTags(base.Tags, override.Tags)
+	merged.Remotes = mergeRemotes(base.Remotes, override.Remotes)
+
+	if override.Enabled != nil {
+		merged.Enabled = override.Enabled
+	}
+
+	if override.Archived != nil {
+		merged.Archived = override.Archived
+	}
+
+	if override.ReadOnly != nil {
+		merged.ReadOnly = override.ReadOnly
+	}
+
+	merged.Groups = append([]Group{}, base.Groups...)
+
+	for _, group := range override.Groups {
+		if i := indexOfGroup(merged.Groups, group.Name); i >= 0 {
+			merged.Groups[i] = mergeGroup(merged.Groups[i], group)
+		} else {
+			merged.Groups = append(merged.Groups, group)
+		}
+	}
+
+	return merged
+}
+
+// mergeGroup deep merges override into base like mergeRepository, repositories and sub-groups are merged
+// by name.
+func mergeGroup(base Group, override Group) Group {
+	merged := base
+
+	merged.NameIgnoredInPath = base.NameIgnoredInPath || override.NameIgnoredInPath
+
+	if override.Path != "" {
+		merged.Path = override.Path
+	}
+
+	if override.EnvFile != "" {
+		merged.EnvFile = override.EnvFile
+	}
+
+	merged.Env = mergeEnv(base.Env, override.Env)
+	merged.Scripts = deepMergeScripts(base.Scripts, override.Scripts)
+	merged.Tags = mergeTags(base.Tags, override.Tags)
+	merged.Remotes = mergeRemotes(base.Remotes, override.Remotes)
+	merged.NoInherit = mergeTags(base.NoInherit, override.NoInherit)
+
+	if override.Enabled != nil {
+		merged.Enabled = override.Enabled
+	}
+
+	if override.Archived != nil {
+		merged.Archived = override.Archived
+	}
+
+	if override.ReadOnly != nil {
+		merged.ReadOnly = override.ReadOnly
+	}
+
+	merged.Repositories = append([]Repository{}, base.Repositories...)
+
+	for _, repository := range override.Repositories {
+		if i := indexOfRepository(merged.Repositories, repository.Name); i >= 0 {
+			merged.Repositories[i] = mergeRepository(merged.Repositories[i], repository)
+		} else {
+			merged.Repositories = append(merged.Repositories, repository)
+		}
+	}
+
+	merged.Groups = append([]Group{}, base.Groups...)
+
+	for _, group := range override.Groups {
+		if i := indexOfGroup(merged.Groups, group.Name); i >= 0 {
+			merged.Groups[i] = mergeGroup(merged.Groups[i], group)
+		} else {
+			merged.Groups = append(merged.Groups, group)
+		}
+	}
+
+	return merged
+}
+
+func indexOfWorkspace(workspaces []Workspace, name string) int {
+	for i, workspace := range workspaces {
+		if workspace.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func indexOfGroup(groups []Group, name string) int {
+	for i, group := range groups {
+		if group.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func indexOfRepository(repositories []Repository, name string) int {
+	for i, repository := range repositories {
+		if repository.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testHost(env map[string]string) Host {
+	return Host{OS: "linux", Hostname: "build-01.example.com", Getenv: func(name string) string { return env[name] }}
+}
+
+func TestOverlayMatches(t *testing.T) {
+	host := testHost(map[string]string{"CI": "true", "TEAM": "payments"})
+
+	cases := []struct {
+		when    OverlayCondition
+		matches bool
+	}{
+		{OverlayCondition{}, false},
+		{OverlayCondition{OS: "linux"}, true},
+		{OverlayCondition{OS: "darwin"}, false},
+		{OverlayCondition{Hostname: "build-*"}, true},
+		{OverlayCondition{Hostname: "laptop-*"}, false},
+		{OverlayCondition{Env: map[string]string{"CI": ""}}, true},
+		{OverlayCondition{Env: map[string]string{"MISSING": ""}}, false},
+		{OverlayCondition{Env: map[string]string{"TEAM": "pay*"}}, true},
+		{OverlayCondition{OS: "linux", Env: map[string]string{"TEAM": "orders"}}, false},
+	}
+
+	for _, c := range cases {
+		if matches := (Overlay{Name: "o", When: c.when}).Matches(host); matches != c.matches {
+			t.Fatalf("expected %#v to match %v, got %v", c.when, c.matches, matches)
+		}
+	}
+}
+
+func TestApplyOverlays(t *testing.T) {
+	config := &Config{
+		Workspaces: []Workspace{{Name: "ws", Path: "/Users/me/ws", Groups: []Group{{Name: "g", Repositories: []Repository{
+			{Name: "r", Tags: []string{"java"}},
+		}}}}},
+		Overlays: []Overlay{
+			{
+				Name: "linux",
+				When: OverlayCondition{OS: "linux"},
+				Workspaces: []Workspace{{Name: "ws", Path: "/home/me/ws", Groups: []Group{{Name: "g", Repositories: []Repository{
+					{Name: "r", Env: map[string]string{"JAVA_HOME": "/usr/lib/jvm/17"}},
+					{Name: "linux-only"},
+				}}}}},
+			},
+			{Name: "darwin", When: OverlayCondition{OS: "darwin"}, Workspaces: []Workspace{{Name: "ws", Path: "/Volumes/ws"}}},
+			{Name: "ci", Workspaces: []Workspace{{Name: "ws", Env: map[string]string{"CI": "true"}}}},
+		},
+	}
+
+	if err := config.ApplyOverlays(testHost(nil), []string{"ci"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(config.AppliedOverlays, []string{"linux", "ci"}) {
+		t.Fatalf("unexpected applied overlays: %#v", config.AppliedOverlays)
+	}
+	if config.Overlays != nil {
+		t.Fatalf("expected overlays to be removed")
+	}
+
+	workspace := config.Workspaces[0]
+	if workspace.Path != "/home/me/ws" {
+		t.Fatalf("unexpected workspace path: %s", workspace.Path)
+	}
+	if !reflect.DeepEqual(workspace.Env, map[string]string{"CI": "true"}) {
+		t.Fatalf("unexpected workspace env: %#v", workspace.Env)
+	}
+
+	repositories := workspace.Groups[0].Repositories
+	if len(repositories) != 2 || repositories[1].Name != "linux-only" {
+		t.Fatalf("unexpected repositories: %#v", repositories)
+	}
+	if !reflect.DeepEqual(repositories[0].Tags, []string{"java"}) || repositories[0].Env["JAVA_HOME"] != "/usr/lib/jvm/17" {
+		t.Fatalf("unexpected repository: %#v", repositories[0])
+	}
+}
+
+func TestApplyOverlays_UndefinedProfile(t *testing.T) {
+	config := &Config{Overlays: []Overlay{{Name: "ci"}}}
+
+	if err := config.ApplyOverlays(testHost(nil), []string{"missing"}); err == nil {
+		t.Fatalf("expected error for undefined profile")
+	}
+}
+
+func TestApplyOverlays_ClearsArchivedAndReadOnly(t *testing.T) {
+	set, cleared := true, false
+	config := &Config{
+		Workspaces: []Workspace{{Name: "ws", ReadOnly: &set, Groups: []Group{{Name: "g", Archived: &set, Repositories: []Repository{
+			{Name: "r", Archived: &set, ReadOnly: &set},
+			{Name: "kept", Archived: &set},
+		}}}}},
+		Overlays: []Overlay{{
+			Name: "maintenance",
+			Workspaces: []Workspace{{Name: "ws", ReadOnly: &cleared, Groups: []Group{{Name: "g", Archived: &cleared, Repositories: []Repository{
+				{Name: "r", Archived: &cleared, ReadOnly: &cleared},
+				{Name: "kept"},
+			}}}}},
+		}},
+	}
+
+	if err := config.ApplyOverlays(testHost(nil), []string{"maintenance"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	workspace := config.Workspaces[0]
+	if workspace.IsReadOnly() || *workspace.Groups[0].Archived {
+		t.Fatalf("expected overlay to clear read-only workspace and archived group: %#v", workspace)
+	}
+
+	repositories := workspace.Groups[0].Repositories
+	if repositories[0].IsArchived() || repositories[0].IsReadOnly() {
+		t.Fatalf("expected overlay to clear archived and read-only repository: %#v", repositories[0])
+	}
+	if !repositories[1].IsArchived() {
+		t.Fatalf("expected archived kept when overlay doesn't set it: %#v", repositories[1])
+	}
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const InheritScripts = "scripts"
+const InheritTags = "tags"
+const InheritRemotes = "remotes"
+
+// Resolve expands the repository templates extended by repositories, then applies the default scripts,
+// tags and remotes declared in workspaces and groups to their repositories. Groups inherit from their
+// workspace and repositories from their group, a repository overrides an inherited script or remote by
+// declaring one with the same name, and opts out of inheritance by listing scripts, tags or remotes in
+// noInherit. Disabled, archived and read-only workspaces and groups make all their repositories so.
+//
+// Nested groups are flattened first: a sub-group is listed after its parent, named by the names of its
+// ancestors delimited by /, and inherits from its parent before the workspace.
+func (c *Config) Resolve() error {
+	for i := range c.Workspaces {
+		c.Workspaces[i].Groups = flattenGroups(c.Workspaces[i].Groups)
+	}
+
+	for i := range c.Workspaces {
+		for j := range c.Workspaces[i].Groups {
+			for k, repository := range c.Workspaces[i].Groups[j].Repositories {
+				extended, err := c.extend(repository, nil)
+				if err != nil {
+					return err
+				}
+
+				c.Workspaces[i].Groups[j].Repositories[k] = extended
+			}
+		}
+	}
+
+	for i := range c.Workspaces {
+		workspace := &c.Workspaces[i]
+
+		for j := range workspace.Groups {
+			group := &workspace.Groups[j]
+
+			inheritLifecycle(
+				&group.Enabled, &group.Archived, &group.ReadOnly, workspace.Enabled, workspace.Archived, workspace.ReadOnly)
+
+			if inherits(group.NoInherit, InheritScripts) {
+				group.Scripts = mergeScripts(workspace.Scripts, group.Scripts)
+			}
+
+			if inherits(group.NoInherit, InheritTags) {
+				group.Tags = mergeTags(workspace.Tags, group.Tags)
+			}
+
+			if inherits(group.NoInherit, InheritRemotes) {
+				group.Remotes = mergeRemotes(workspace.Remotes, group.Remotes)
+			}
+
+			for k := range group.Repositories {
+				repository := &group.Repositories[k]
+
+				inheritLifecycle(
+					&repository.Enabled, &repository.Archived, &repository.ReadOnly, group.Enabled, group.Archived, group.ReadOnly)
+
+				if inherits(repository.NoInherit, InheritScripts) {
+					repository.Scripts = mergeScripts(group.Scripts, repository.Scripts)
+				}
+
+				if inherits(repository.NoInherit, InheritTags) {
+					repository.Tags = mergeTags(group.Tags, repository.Tags)
+				}
+
+				if inherits(repository.NoInherit, InheritRemotes) {
+					repository.Remotes = mergeRemotes(group.Remotes, repository.Remotes)
+				}
+
+				for l := range repository.Remotes {
+					repository.Remotes[l].URL = expandRemoteURL(repository.Remotes[l].URL, *workspace, *group, *repository)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// flattenGroups returns groups with their sub-groups, recursively, each sub-group following its parent.
+// Paths of sub-groups are composed with the path of their parent, relative to workspace path.
+func flattenGroups(groups []Group) []Group {
+	var flattened []Group
+
+	for _, group := range groups {
+		subGroups := group.Groups
+		group.Groups = nil
+
+		flattened = append(flattened, group)
+
+		for _, subGroup := range flattenGroups(subGroups) {
+			flattened = append(flattened, inheritGroup(group, subGroup))
+		}
+	}
+
+	return flattened
+}
+
+// inheritGroup returns group, already flattened, as sub-group of parent.
+func inheritGroup(parent Group, group Group) Group {
+	if !filepath.IsAbs(group.Path) && !strings.HasPrefix(group.Path, "/") {
+		group.Path = filepath.ToSlash(filepath.Join(relativeGroupPath(parent), relativeGroupPath(group)))
+
+		// The parent and group both ignored their names, the group lives in workspace path.
+		if group.Path == "." {
+			group.Path = ""
+			group.NameIgnoredInPath = true
+		} else {
+			group.NameIgnoredInPath = false
+		}
+	}
+
+	group.Name = parent.Name + "/" + group.Name
+
+	if inherits(group.NoInherit, InheritScripts) {
+		group.Scripts = mergeScripts(parent.Scripts, group.Scripts)
+	}
+
+	if inherits(group.NoInherit, InheritTags) {
+		group.Tags = mergeTags(parent.Tags, group.Tags)
+	}
+
+	if inherits(group.NoInherit, InheritRemotes) {
+		group.Remotes = mergeRemotes(parent.Remotes, group.Remotes)
+	}
+
+	// What the parent didn't inherit from the workspace, its sub-groups don't either.
+	group.NoInherit = mergeTags(parent.NoInherit, group.NoInherit)
+	group.Env = mergeEnv(parent.Env, group.Env)
+
+	// The env file of the parent comes before the env files the group inherited already, all of them are
+	// relative to the parent path like the path of the group.
+	envFiles := group.InheritedEnvFiles
+	if parent.EnvFile != "" {
+		envFiles = append([]string{parent.EnvFile}, envFiles...)
+	}
+
+	group.InheritedEnvFiles = nil
+	for _, envFile := range envFiles {
+		envFile = os.ExpandEnv(envFile)
+
+		if !filepath.IsAbs(envFile) && !strings.HasPrefix(envFile, "/") {
+			envFile = filepath.ToSlash(filepath.Join(relativeGroupPath(parent), envFile))
+		}
+
+		group.InheritedEnvFiles = append(group.InheritedEnvFiles, envFile)
+	}
+
+	inheritLifecycle(&group.Enabled, &group.Archived, &group.ReadOnly, parent.Enabled, parent.Archived, parent.ReadOnly)
+
+	return group
+}
+
+// relativeGroupPath returns the path of group relative to its parent.
+func relativeGroupPath(group Group) string {
+	if group.Path != "" {
+		return group.Path
+	}
+
+	if group.NameIgnoredInPath {
+		return ""
+	}
+
+	return group.Name
+}
+
+// inheritLifecycle disables, archives or makes read-only a group or repository when its parent is.
+func inheritLifecycle(
+	enabled **bool, archived **bool, readOnly **bool, parentEnabled *bool, parentArchived *bool, parentReadOnly *bool) {
+	if parentEnabled != nil && !*parentEnabled {
+		*enabled = parentEnabled
+	}
+
+	if parentArchived != nil && *parentArchived {
+		*archived = parentArchived
+	}
+
+	if parentReadOnly != nil && *parentReadOnly {
+		*readOnly = parentReadOnly
+	}
+}
+
+func inherits(noInherit []string, kind string) bool {
+	for _, current := range noInherit {
+		if strings.EqualFold(current, kind) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// mergeScripts returns inherited scripts followed by own scripts, an own script replaces the inherited
+// script with the same name in place.
+func mergeScripts(inherited []Script, own []Script) []Script {
+	var scripts []Script
+
+	for _, script := range inherited {
+		if overridden, found := findScript(own, script.Name); found {
+			scripts = append(scripts, overridden)
+		} else {
+			scripts = append(scripts, script)
+		}
+	}
+
+	for _, script := range own {
+		if _, found := findScript(inherited, script.Name); !found {
+			scripts = append(scripts, script)
+		}
+	}
+
+	return scripts
+}
+
+func findScript(scripts []Script, name string) (Script, bool) {
+	for _, script := range scripts {
+		if script.Name == name {
+			return script, true
+		}
+	}
+
+	return Script{}, false
+}
+
+func mergeTags(inherited []string, own []string) []string {
+	var tags []string
+
+	for _, tag := range append(append([]string{}, inherited...), own...) {
+		var exists bool
+
+		for _, current := range tags {
+			if current == tag {
+				exists = true
+			}
+		}
+
+		if !exists {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// mergeRemotes returns inherited remotes followed by own remotes, an own remote replaces the inherited
+// remote with the same name in place.
+func mergeRemotes(inherited []GitRemote, own []GitRemote) []GitRemote {
+	var remotes []GitRemote
+
+	for _, remote := range inherited {
+		if overridden, found := findRemote(own, remote.Name); found {
+			remotes = append(remotes, overridden)
+		} else {
+			remotes = append(remotes, remote)
+		}
+	}
+
+	for _, remote := range own {
+		if _, found := findRemote(inherited, remote.Name); !found {
+			remotes = append(remotes, remote)
+		}
+	}
+
+	return remotes
+}
+
+func findRemote(remotes []GitRemote, name string) (GitRemote, bool) {
+	for _, remote := range remotes {
+		if remote.Name == name {
+			return remote, true
+		}
+	}
+
+	return GitRemote{}, false
+}
+
+// expandRemoteURL replaces ${workspace.name}, ${group.name} and ${repository.name} in url.
+func expandRemoteURL(url string, workspace Workspace, group Group, repository Repository) string {
+	return strings.NewReplacer(
+		"${workspace.name}", workspace.Name,
+		"${group.name}", group.Name,
+		"${repository.name}", repository.Name,
+	).Replace(url)
+}
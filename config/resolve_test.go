@@ -0,0 +1,172 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolve_InheritsWorkspaceAndGroupDefaults(t *testing.T) {
+	config := &Config{Workspaces: []Workspace{{
+		Name:    "keepnative",
+		Scripts: []Script{{Name: "mvn"}},
+		Tags:    []string{"java"},
+		Remotes: []GitRemote{{Name: "origin", URL: "git@gitlab.com:${workspace.name}/${repository.name}.git"}},
+		Groups: []Group{{
+			Name: "next",
+			Tags: []string{"next"},
+			Repositories: []Repository{
+				{Name: "java"},
+				{
+					Name:    "soupe",
+					Scripts: []Script{{Name: "mvn", Default: true}, {Name: "npm"}},
+					Tags:    []string{"ui", "java"},
+					Remotes: []GitRemote{{Name: "origin", URL: "git@github.com:keepnative/soupe.git"}},
+				},
+				{Name: "legacy", NoInherit: []string{InheritScripts, InheritRemotes}},
+			},
+		}},
+	}}}
+
+	if err := config.Resolve(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	repositories := config.Workspaces[0].Groups[0].Repositories
+
+	java := repositories[0]
+	if !reflect.DeepEqual(java.Scripts, []Script{{Name: "mvn"}}) {
+		t.Fatalf("unexpected inherited scripts: %#v", java.Scripts)
+	}
+	if !reflect.DeepEqual(java.Tags, []string{"java", "next"}) {
+		t.Fatalf("unexpected inherited tags: %#v", java.Tags)
+	}
+	if len(java.Remotes) != 1 || java.Remotes[0].URL != "git@gitlab.com:keepnative/java.git" {
+		t.Fatalf("unexpected inherited remotes: %#v", java.Remotes)
+	}
+
+	soupe := repositories[1]
+	if !reflect.DeepEqual(soupe.Scripts, []Script{{Name: "mvn", Default: true}, {Name: "npm"}}) {
+		t.Fatalf("unexpected overridden scripts: %#v", soupe.Scripts)
+	}
+	if !reflect.DeepEqual(soupe.Tags, []string{"java", "next", "ui"}) {
+		t.Fatalf("unexpected merged tags: %#v", soupe.Tags)
+	}
+	if len(soupe.Remotes) != 1 || soupe.Remotes[0].URL != "git@github.com:keepnative/soupe.git" {
+		t.Fatalf("unexpected overridden remotes: %#v", soupe.Remotes)
+	}
+
+	legacy := repositories[2]
+	if len(legacy.Scripts) != 0 || len(legacy.Remotes) != 0 {
+		t.Fatalf("expected legacy to opt out of scripts and remotes: %#v", legacy)
+	}
+	if !reflect.DeepEqual(legacy.Tags, []string{"java", "next"}) {
+		t.Fatalf("expected legacy to inherit tags: %#v", legacy.Tags)
+	}
+}
+
+func TestResolve_LifecycleFlagsPropagate(t *testing.T) {
+	disabled, archived, readOnly := false, true, true
+	config := &Config{Workspaces: []Workspace{{Name: "ws", ReadOnly: &readOnly, Groups: []Group{
+		{Name: "retired", Enabled: &disabled, Repositories: []Repository{{Name: "a"}}},
+		{Name: "active", Repositories: []Repository{{Name: "b"}, {Name: "c", Archived: &archived}}},
+	}}}}
+
+	if err := config.Resolve(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := config.Workspaces[0].Groups[0].Repositories[0]
+	b := config.Workspaces[0].Groups[1].Repositories[0]
+	c := config.Workspaces[0].Groups[1].Repositories[1]
+
+	if a.IsEnabled() || !b.IsEnabled() || !c.IsEnabled() {
+		t.Fatalf("unexpected enabled: %v %v %v", a.IsEnabled(), b.IsEnabled(), c.IsEnabled())
+	}
+	if a.IsArchived() || b.IsArchived() || !c.IsArchived() {
+		t.Fatalf("unexpected archived: %v %v %v", a.IsArchived(), b.IsArchived(), c.IsArchived())
+	}
+	if !a.IsReadOnly() || !b.IsReadOnly() || !c.IsReadOnly() {
+		t.Fatalf("expected read-only inherited from workspace")
+	}
+}
+
+func TestResolve_NestedGroups(t *testing.T) {
+	config := &Config{Workspaces: []Workspace{{Name: "ws", Tags: []string{"ws"}, Groups: []Group{
+		{
+			Name:    "spring-cloud",
+			Tags:    []string{"spring"},
+			Scripts: []Script{{Name: "mvn", Default: true}},
+			Groups: []Group{
+				{Name: "deployers", Tags: []string{"deployer"}, Groups: []Group{
+					{Name: "kubernetes", NameIgnoredInPath: true, Repositories: []Repository{{Name: "deployer-kubernetes"}}},
+				}},
+				{Name: "absolute", Path: "/opt/absolute"},
+			},
+		},
+	}}}}
+
+	if err := config.Resolve(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, group := range config.Workspaces[0].Groups {
+		names = append(names, group.Name)
+	}
+	if !reflect.DeepEqual(names, []string{
+		"spring-cloud", "spring-cloud/deployers", "spring-cloud/deployers/kubernetes", "spring-cloud/absolute",
+	}) {
+		t.Fatalf("unexpected flattened groups: %#v", names)
+	}
+
+	kubernetes := config.Workspaces[0].Groups[2]
+	if kubernetes.Path != "spring-cloud/deployers" || kubernetes.NameIgnoredInPath {
+		t.Fatalf("unexpected path of nested group: %q %v", kubernetes.Path, kubernetes.NameIgnoredInPath)
+	}
+	if config.Workspaces[0].Groups[3].Path != "/opt/absolute" {
+		t.Fatalf("expected absolute path kept: %s", config.Workspaces[0].Groups[3].Path)
+	}
+
+	repository := kubernetes.Repositories[0]
+	if !reflect.DeepEqual(repository.Tags, []string{"ws", "spring", "deployer"}) {
+		t.Fatalf("unexpected inherited tags: %#v", repository.Tags)
+	}
+	if !reflect.DeepEqual(repository.Scripts, []Script{{Name: "mvn", Default: true}}) {
+		t.Fatalf("unexpected inherited scripts: %#v", repository.Scripts)
+	}
+}
+
+func TestResolve_NestedGroupsInheritEnvFiles(t *testing.T) {
+	config := &Config{Workspaces: []Workspace{{Name: "ws", Groups: []Group{
+		{Name: "spring-cloud", EnvFile: ".env", Groups: []Group{
+			{Name: "deployers", EnvFile: "env/deployers.env", Groups: []Group{
+				{Name: "kubernetes", EnvFile: ".env"},
+			}},
+			{Name: "absolute", Path: "/opt/absolute", EnvFile: "/etc/absolute.env", Groups: []Group{
+				{Name: "nested"},
+			}},
+		}},
+	}}}}
+
+	if err := config.Resolve(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	groups := config.Workspaces[0].Groups
+
+	kubernetes := groups[2]
+	if kubernetes.Name != "spring-cloud/deployers/kubernetes" || kubernetes.EnvFile != ".env" {
+		t.Fatalf("unexpected group: %s %s", kubernetes.Name, kubernetes.EnvFile)
+	}
+	if !reflect.DeepEqual(kubernetes.InheritedEnvFiles, []string{"spring-cloud/.env", "spring-cloud/deployers/env/deployers.env"}) {
+		t.Fatalf("unexpected inherited env files: %#v", kubernetes.InheritedEnvFiles)
+	}
+
+	nested := groups[4]
+	if !reflect.DeepEqual(nested.InheritedEnvFiles, []string{"spring-cloud/.env", "/etc/absolute.env"}) {
+		t.Fatalf("unexpected inherited env files: %#v", nested.InheritedEnvFiles)
+	}
+	if groups[0].InheritedEnvFiles != nil {
+		t.Fatalf("expected no inherited env files of top group: %#v", groups[0].InheritedEnvFiles)
+	}
+}
@@ -0,0 +1,301 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema returns the JSON Schema of the configuration, generated from the yaml, jsonschema and description
+// tags of the config structs. The same schema validates configuration files when they are loaded.
+func Schema() map[string]interface{} {
+	definitions := map[string]interface{}{}
+
+	schema := typeSchema(reflect.TypeOf(Config{}), definitions)
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "Handy CI configuration"
+	schema["definitions"] = definitions
+
+	return schema
+}
+
+func typeSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), definitions)}
+	case reflect.Map:
+		// Scalars of maps, such as env variables, are decoded as strings.
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": []interface{}{"string", "number", "boolean"}},
+		}
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), definitions)
+	case reflect.Struct:
+		if t == reflect.TypeOf(Config{}) {
+			return structSchema(t, definitions)
+		}
+
+		if _, defined := definitions[t.Name()]; !defined {
+			// Reserve the name first, so that recursive types refer to themselves.
+			definitions[t.Name()] = nil
+			definitions[t.Name()] = structSchema(t, definitions)
+		}
+
+		return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+	}
+
+	return map[string]interface{}{}
+}
+
+func structSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+
+	var required []interface{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		property := typeSchema(field.Type, definitions)
+
+		if description := field.Tag.Get("description"); description != "" {
+			if _, isRef := property["$ref"]; isRef {
+				property = map[string]interface{}{"allOf": []interface{}{property}}
+			}
+
+			property["description"] = description
+		}
+
+		for _, option := range strings.Split(field.Tag.Get("jsonschema"), ",") {
+			key, value, _ := strings.Cut(option, "=")
+
+			switch key {
+			case "required":
+				required = append(required, name)
+			case "pattern":
+				property["pattern"] = value
+			case "enum":
+				var enum []interface{}
+				for _, item := range strings.Split(value, "|") {
+					enum = append(enum, item)
+				}
+
+				if property["type"] == "array" {
+					property["items"] = map[string]interface{}{"type": "string", "enum": enum}
+				} else {
+					property["enum"] = enum
+				}
+			}
+		}
+
+		properties[name] = property
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// ValidateDocument validates document against Schema.
+func ValidateDocument(document *yaml.Node) error {
+	var value interface{}
+
+	err := document.Decode(&value)
+	if err != nil {
+		return err
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	schema := Schema()
+
+	var problems []string
+
+	validateValue(schema, schema, value, "", &problems)
+
+	if len(problems) > 0 {
+		return ValidationError{problems}
+	}
+
+	return nil
+}
+
+// validateValue validates value against the subset of JSON Schema generated by Schema.
+func validateValue(root map[string]interface{}, schema map[string]interface{}, value interface{}, path string, problems *[]string) {
+	// A key without value is the same as a missing key.
+	if value == nil {
+		return
+	}
+
+	at := path
+	if at == "" {
+		at = "config"
+	}
+
+	if ref, isRef := schema["$ref"].(string); isRef {
+		definition, _ := root["definitions"].(map[string]interface{})[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{})
+		validateValue(root, definition, value, path, problems)
+		return
+	}
+
+	if allOf, isAllOf := schema["allOf"].([]interface{}); isAllOf {
+		for _, item := range allOf {
+			validateValue(root, item.(map[string]interface{}), value, path, problems)
+		}
+	}
+
+	if schemaType, hasType := schema["type"]; hasType && !matchesType(schemaType, value) {
+		*problems = append(*problems, fmt.Sprintf("%s should be %v", at, describeType(schemaType)))
+		return
+	}
+
+	if enum, hasEnum := schema["enum"].([]interface{}); hasEnum {
+		var matched bool
+
+		for _, item := range enum {
+			if item == value {
+				matched = true
+			}
+		}
+
+		if !matched {
+			*problems = append(*problems, fmt.Sprintf("%s should be one of %v", at, enum))
+		}
+	}
+
+	if pattern, hasPattern := schema["pattern"].(string); hasPattern {
+		if text, isString := value.(string); isString && !regexp.MustCompile(pattern).MatchString(text) {
+			*problems = append(*problems, fmt.Sprintf("%s %q should match %s", at, text, pattern))
+		}
+	}
+
+	switch typed := value.(type) {
+	case []interface{}:
+		if items, hasItems := schema["items"].(map[string]interface{}); hasItems {
+			for i, item := range typed {
+				validateValue(root, items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+
+		if required, hasRequired := schema["required"].([]interface{}); hasRequired {
+			for _, name := range required {
+				if _, present := typed[name.(string)]; !present {
+					*problems = append(*problems, fmt.Sprintf("%s requires %s", at, name))
+				}
+			}
+		}
+
+		var keys []string
+		for key := range typed {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			property, known := properties[key].(map[string]interface{})
+
+			if !known {
+				switch additional := schema["additionalProperties"].(type) {
+				case bool:
+					if !additional {
+						*problems = append(*problems, fmt.Sprintf("%s has unknown key %s", at, key))
+					}
+				case map[string]interface{}:
+					validateValue(root, additional, typed[key], joinPath(path, key), problems)
+				}
+
+				continue
+			}
+
+			validateValue(root, property, typed[key], joinPath(path, key), problems)
+		}
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func matchesType(schemaType interface{}, value interface{}) bool {
+	if types, isList := schemaType.([]interface{}); isList {
+		for _, item := range types {
+			if matchesType(item, value) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	switch schemaType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		_, ok := value.(int)
+		return ok
+	case "number":
+		switch value.(type) {
+		case int, float64:
+			return true
+		}
+
+		return false
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+
+	return true
+}
+
+func describeType(schemaType interface{}) string {
+	if types, isList := schemaType.([]interface{}); isList {
+		var names []string
+		for _, item := range types {
+			names = append(names, fmt.Sprint(item))
+		}
+
+		return strings.Join(names, " or ")
+	}
+
+	return fmt.Sprint(schemaType)
+}
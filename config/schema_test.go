@@ -0,0 +1,156 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchema_GeneratedFromStructs(t *testing.T) {
+	schema := Schema()
+
+	content, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("schema should be valid JSON: %v", err)
+	}
+
+	definitions := schema["definitions"].(map[string]interface{})
+	repository := definitions["Repository"].(map[string]interface{})
+	if repository["additionalProperties"] != false {
+		t.Fatalf("expected unknown keys to be rejected: %s", content)
+	}
+	if required := repository["required"].([]interface{}); len(required) != 1 || required[0] != "name" {
+		t.Fatalf("expected name to be required: %v", required)
+	}
+	properties := repository["properties"].(map[string]interface{})
+	if properties["name"].(map[string]interface{})["pattern"] == nil {
+		t.Fatalf("expected pattern for name: %v", properties["name"])
+	}
+	if properties["tags"].(map[string]interface{})["description"] != "Tags to filter repositories with" {
+		t.Fatalf("expected description for tags: %v", properties["tags"])
+	}
+	noInherit := properties["noInherit"].(map[string]interface{})["items"].(map[string]interface{})
+	if len(noInherit["enum"].([]interface{})) != 3 {
+		t.Fatalf("expected enum for noInherit: %v", noInherit)
+	}
+}
+
+func TestValidateDocument(t *testing.T) {
+	valid := parseDocument(t, `version: 2
+scriptDefinitions:
+  - name: ./gradlew
+    steps:
+      - run: ./gradlew build
+workspaces:
+  - name: keepnative
+    path: /coding/keepnative
+    env:
+      PORT: 8080
+    groups:
+      - name: next
+        repositories:
+          - name: .handy-ci
+            noInherit: [scripts]
+            remotes:
+              - name: origin
+                url: git@host:handy-ci.git
+`)
+	if err := ValidateDocument(valid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	invalid := parseDocument(t, `workspaces:
+  - name: keep native
+    root: /coding/keepnative
+    groups:
+      - name: next
+        nameIgnoredInPath: yes please
+        repositories:
+          - name: java
+            noInherit: [everything]
+            remotes:
+              - name: origin
+          - path: soupe
+scriptDefinitions:
+  - name: build
+    steps:
+      - name: compile
+`)
+	err := ValidateDocument(invalid)
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, expected := range []string{
+		`workspaces[0].name "keep native" should match`,
+		"workspaces[0] has unknown key root",
+		"workspaces[0].groups[0].nameIgnoredInPath should be boolean",
+		"workspaces[0].groups[0].repositories[0].noInherit[0] should be one of",
+		"workspaces[0].groups[0].repositories[0].remotes[0] requires url",
+		"workspaces[0].groups[0].repositories[1] requires name",
+		"scriptDefinitions[0].steps[0] requires run",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in %v", expected, err)
+		}
+	}
+}
+
+func TestLoad_ValidatesJSONAndTOML(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{"version": 2, "workspaces": [{"name": "keepnative", "root": "/coding",
+  "env": {"JAVA_HOME": "/jdk17"}, "groups": [{"name": "next", "nameIgnoredInPath": "yes please"}]}]}`,
+		"config.toml": `version = 2
+
+[[workspaces]]
+name = "keepnative"
+root = "/coding"
+
+[workspaces.env]
+JAVA_HOME = "/jdk17"
+
+[[workspaces.groups]]
+name = "next"
+nameIgnoredInPath = "yes please"
+`,
+	}
+
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		config, err := Load(file)
+		if err == nil {
+			t.Fatalf("expected validation error of %s", name)
+		}
+		for _, expected := range []string{
+			"workspaces[0] has unknown key root",
+			"workspaces[0].groups[0].nameIgnoredInPath should be boolean",
+		} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Fatalf("expected %q in %v of %s", expected, err, name)
+			}
+		}
+
+		if config != nil {
+			t.Fatalf("expected no config of %s which can't be decoded, got %#v", name, config)
+		}
+
+		valid := strings.NewReplacer(`"root": "/coding",`, "", `root = "/coding"`, "", `"yes please"`, "true").Replace(content)
+		if err := os.WriteFile(file, []byte(valid), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		config, err = Load(file)
+		if err != nil {
+			t.Fatalf("unexpected error of %s: %v", name, err)
+		}
+		if config.Workspaces[0].Env["JAVA_HOME"] != "/jdk17" {
+			t.Fatalf("expected env keys of %s kept, got %#v", name, config.Workspaces[0].Env)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// extend merges the templates listed in extends of repository, in order, and then repository itself.
+// Templates can extend other templates, visited holds the chain of templates being expanded.
+func (c *Config) extend(repository Repository, visited []string) (Repository, error) {
+	if len(repository.Extends) == 0 {
+		return repository, nil
+	}
+
+	var extended Repository
+
+	for _, templateName := range repository.Extends {
+		for _, visitedName := range visited {
+			if visitedName == templateName {
+				return Repository{}, fmt.Errorf(
+					"repository template cycle %s", strings.Join(append(visited, templateName), " -> "))
+			}
+		}
+
+		template, found := c.findRepositoryTemplate(templateName)
+		if !found {
+			return Repository{}, fmt.Errorf(
+				"repository template %s extended by %s not defined", templateName, repository.Name)
+		}
+
+		template, err := c.extend(template, append(visited, templateName))
+		if err != nil {
+			return Repository{}, err
+		}
+
+		extended = mergeRepository(extended, template)
+	}
+
+	extended = mergeRepository(extended, repository)
+	extended.Name = repository.Name
+	extended.Extends = repository.Extends
+
+	return extended, nil
+}
+
+func (c *Config) findRepositoryTemplate(name string) (Repository, bool) {
+	for _, template := range c.RepositoryTemplates {
+		if template.Name == name {
+			return template, true
+		}
+	}
+
+	return Repository{}, false
+}
+
+// mergeRepository deep merges override into base. Scalars of override replace non-empty scalars of base,
+// lists are merged by name or value and maps are merged by key.
+func mergeRepository(base Repository, override Repository) Repository {
+	merged := base
+
+	merged.Name = override.Name
+	merged.NameIgnoredInPath = base.NameIgnoredInPath || override.NameIgnoredInPath
+
+	if override.Path != "" {
+		merged.Path = override.Path
+	}
+
+	if override.EnvFile != "" {
+		merged.EnvFile = override.EnvFile
+	}
+
+	merged.Remotes = mergeRemotes(base.Remotes, override.Remotes)
+	merged.Scripts = deepMergeScripts(base.Scripts, override.Scripts)
+	merged.Tags = mergeTags(base.Tags, override.Tags)
+	merged.Env = mergeEnv(base.Env, override.Env)
+	merged.NoInherit = mergeTags(base.NoInherit, override.NoInherit)
+	merged.Extends = mergeTags(base.Extends, override.Extends)
+	merged.DependsOn = mergeTags(base.DependsOn, override.DependsOn)
+
+	if override.Enabled != nil {
+		merged.Enabled = override.Enabled
+	}
+
+	if override.Archived != nil {
+		merged.Archived = override.Archived
+	}
+
+	if override.ReadOnly != nil {
+		merged.ReadOnly = override.ReadOnly
+	}
+
+	return merged
+}
+
+// deepMergeScripts merges scripts by name, paths and env of scripts with the same name are merged, env
+// file and timeout are overridden.
+func deepMergeScripts(base []Script, override []Script) []Script {
+	var scripts []Script
+
+	for _, script := range base {
+		if overridden, found := findScript(override, script.Name); found {
+			script.Default = script.Default || overridden.Default
+			script.Paths = mergeTags(script.Paths, overridden.Paths)
+			script.Env = mergeEnv(script.Env, overridden.Env)
+
+			if overridden.EnvFile != "" {
+				script.EnvFile = overridden.EnvFile
+			}
+
+			if overridden.Timeout != "" {
+				script.Timeout = overridden.Timeout
+			}
+		}
+
+		scripts = append(scripts, script)
+	}
+
+	for _, script := range override {
+		if _, found := findScript(base, script.Name); !found {
+			scripts = append(scripts, script)
+		}
+	}
+
+	return scripts
+}
+
+func mergeEnv(base map[string]string, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+
+	merged := map[string]string{}
+
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range override {
+		merged[key] = value
+	}
+
+	return merged
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolve_ExtendsRepositoryTemplates(t *testing.T) {
+	config := &Config{
+		RepositoryTemplates: []Repository{
+			{Name: "java", Tags: []string{"java"}, Scripts: []Script{{Name: "mvn", Default: true}}, Env: map[string]string{"JAVA_HOME": "/jdk17"}},
+			{
+				Name:    "spring-boot-service",
+				Extends: []string{"java"},
+				Tags:    []string{"service"},
+				Scripts: []Script{{Name: "mvn", Paths: []string{"service"}}},
+				Env:     map[string]string{"SPRING_PROFILES_ACTIVE": "local"},
+			},
+		},
+		Workspaces: []Workspace{{Name: "ws", Groups: []Group{{Name: "g", Repositories: []Repository{
+			{Name: "orders", Extends: []string{"spring-boot-service"}, Tags: []string{"orders"}, Env: map[string]string{"JAVA_HOME": "/jdk21"}},
+		}}}}},
+	}
+
+	if err := config.Resolve(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	orders := config.Workspaces[0].Groups[0].Repositories[0]
+	if orders.Name != "orders" {
+		t.Fatalf("expected name of repository to be kept, got %s", orders.Name)
+	}
+	if !reflect.DeepEqual(orders.Tags, []string{"java", "service", "orders"}) {
+		t.Fatalf("unexpected tags: %#v", orders.Tags)
+	}
+	if !reflect.DeepEqual(orders.Scripts, []Script{{Name: "mvn", Default: true, Paths: []string{"service"}}}) {
+		t.Fatalf("unexpected scripts: %#v", orders.Scripts)
+	}
+	if !reflect.DeepEqual(orders.Env, map[string]string{"JAVA_HOME": "/jdk21", "SPRING_PROFILES_ACTIVE": "local"}) {
+		t.Fatalf("unexpected env: %#v", orders.Env)
+	}
+}
+
+func TestResolve_RepositoryTemplateErrors(t *testing.T) {
+	undefined := &Config{Workspaces: []Workspace{{Name: "ws", Groups: []Group{{Name: "g", Repositories: []Repository{
+		{Name: "r", Extends: []string{"missing"}},
+	}}}}}}
+	if err := undefined.Resolve(); err == nil {
+		t.Fatalf("expected error for undefined template")
+	}
+
+	cycle := &Config{
+		RepositoryTemplates: []Repository{{Name: "a", Extends: []string{"b"}}, {Name: "b", Extends: []string{"a"}}},
+		Workspaces: []Workspace{{Name: "ws", Groups: []Group{{Name: "g", Repositories: []Repository{
+			{Name: "r", Extends: []string{"a"}},
+		}}}}},
+	}
+	if err := cycle.Resolve(); err == nil {
+		t.Fatalf("expected error for template cycle")
+	}
+}
+
+func TestDeepMergeScripts_Timeout(t *testing.T) {
+	base := []Script{{Name: "mvn", Timeout: "10m"}, {Name: "npm", Timeout: "5m"}}
+	override := []Script{{Name: "mvn", Timeout: "30m"}, {Name: "npm", Paths: []string{"web"}}}
+
+	expected := []Script{{Name: "mvn", Timeout: "30m"}, {Name: "npm", Paths: []string{"web"}, Timeout: "5m"}}
+	if scripts := deepMergeScripts(base, override); !reflect.DeepEqual(scripts, expected) {
+		t.Fatalf("unexpected scripts: %#v", scripts)
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ValidationError lists the problems found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e ValidationError) Error() string {
+	return "invalid config, " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the rules of configuration that Schema can't express: names unique in their parent,
+// extended repository templates defined and retries of script definitions valid.
+func (c *Config) Validate() error {
+	var problems []string
+
+	problem := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	scriptDefinitionNames := map[string]bool{}
+	for _, scriptDefinition := range c.ScriptDefinitions {
+		if scriptDefinitionNames[scriptDefinition.Name] {
+			problem("script definition %s defined more than once", scriptDefinition.Name)
+		}
+
+		scriptDefinitionNames[scriptDefinition.Name] = true
+
+		if scriptDefinition.Retry.Attempts < 0 {
+			problem("retry attempts of script definition %s should not be negative", scriptDefinition.Name)
+		}
+
+		if _, err := regexp.Compile(scriptDefinition.Retry.OnOutputMatch); err != nil {
+			problem("retry onOutputMatch of script definition %s is invalid, %v", scriptDefinition.Name, err)
+		}
+	}
+
+	templateNames := map[string]bool{}
+	for _, template := range c.RepositoryTemplates {
+		if templateNames[template.Name] {
+			problem("repository template %s defined more than once", template.Name)
+		}
+
+		templateNames[template.Name] = true
+	}
+
+	for _, template := range c.RepositoryTemplates {
+		validateRepository(template, "repository template "+template.Name, templateNames, problem)
+	}
+
+	workspaceNames := map[string]bool{}
+	for _, workspace := range c.Workspaces {
+		if workspaceNames[workspace.Name] {
+			problem("workspace %s defined more than once", workspace.Name)
+		}
+
+		workspaceNames[workspace.Name] = true
+
+		validateRemotes(workspace.Remotes, "workspace "+workspace.Name, problem)
+
+		validateGroups(workspace.Groups, "workspace "+workspace.Name, "", templateNames, problem)
+		validateDependencies(workspace, problem)
+	}
+
+	if len(problems) > 0 {
+		return ValidationError{problems}
+	}
+
+	return nil
+}
+
+// validateGroups validates groups and their sub-groups, prefix is the path of the parent group.
+func validateGroups(
+	groups []Group, context string, prefix string, templateNames map[string]bool, problem func(string, ...interface{})) {
+	groupNames := map[string]bool{}
+	for _, group := range groups {
+		if groupNames[group.Name] {
+			problem("group %s defined more than once in %s", prefix+group.Name, context)
+		}
+
+		groupNames[group.Name] = true
+
+		validateRemotes(group.Remotes, "group "+prefix+group.Name, problem)
+
+		repositoryNames := map[string]bool{}
+		for _, repository := range group.Repositories {
+			if repositoryNames[repository.Name] {
+				problem("repository %s defined more than once in group %s", repository.Name, prefix+group.Name)
+			}
+
+			repositoryNames[repository.Name] = true
+
+			validateRepository(repository, "repository "+repository.Name, templateNames, problem)
+		}
+
+		validateGroups(group.Groups, "group "+prefix+group.Name, prefix+group.Name+"/", templateNames, problem)
+	}
+}
+
+// validateDependencies checks that repositories depend on repositories of their workspace.
+func validateDependencies(workspace Workspace, problem func(string, ...interface{})) {
+	repositoryNames := map[string]bool{}
+
+	var repositories []Repository
+
+	var collect func(groups []Group)
+	collect = func(groups []Group) {
+		for _, group := range groups {
+			for _, repository := range group.Repositories {
+				repositoryNames[repository.Name] = true
+				repositories = append(repositories, repository)
+			}
+
+			collect(group.Groups)
+		}
+	}
+
+	collect(workspace.Groups)
+
+	for _, repository := range repositories {
+		for _, dependency := range repository.DependsOn {
+			if !repositoryNames[dependency] {
+				problem("repository %s depends on %s not defined in workspace %s", repository.Name, dependency, workspace.Name)
+			}
+		}
+	}
+}
+
+func validateRepository(
+	repository Repository, context string, templateNames map[string]bool, problem func(string, ...interface{})) {
+	validateRemotes(repository.Remotes, context, problem)
+
+	for _, templateName := range repository.Extends {
+		if !templateNames[templateName] {
+			problem("%s extends undefined repository template %s", context, templateName)
+		}
+	}
+}
+
+func validateRemotes(remotes []GitRemote, context string, problem func(string, ...interface{})) {
+	remoteNames := map[string]bool{}
+
+	for _, remote := range remotes {
+		if remoteNames[remote.Name] {
+			problem("remote %s defined more than once in %s", remote.Name, context)
+		}
+
+		remoteNames[remote.Name] = true
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &Config{Workspaces: []Workspace{{Name: "ws", Groups: []Group{{Name: "g", Repositories: []Repository{
+		{Name: "r", Remotes: []GitRemote{{Name: "origin", URL: "git@host:r.git"}}},
+	}}}}}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	invalid := &Config{
+		Workspaces: []Workspace{{Name: "ws", Groups: []Group{{Name: "g", Repositories: []Repository{
+			{Name: "r", Remotes: []GitRemote{{Name: "origin", URL: "a"}, {Name: "origin", URL: "b"}}},
+			{Name: "r", Extends: []string{"missing"}},
+		}}}}},
+	}
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, expected := range []string{"remote origin defined more than once", "repository r defined more than once", "undefined repository template"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in %v", expected, err)
+		}
+	}
+}
+
+func TestValidate_UndefinedDependency(t *testing.T) {
+	config := &Config{Workspaces: []Workspace{{Name: "ws", Groups: []Group{{Name: "g", Repositories: []Repository{
+		{Name: "app", DependsOn: []string{"lib"}},
+	}}}}}}
+
+	err := config.Validate()
+	if err == nil || !strings.Contains(err.Error(), "repository app depends on lib not defined") {
+		t.Fatalf("expected undefined dependency error, got %v", err)
+	}
+}
+
+func TestValidate_Retry(t *testing.T) {
+	config := &Config{ScriptDefinitions: []ScriptDefinition{
+		{Name: "npm", Retry: Retry{Attempts: -1}},
+		{Name: "git", Retry: Retry{Attempts: 3, OnOutputMatch: "("}},
+	}}
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, expected := range []string{"retry attempts of script definition npm", "retry onOutputMatch of script definition git"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in %v", expected, err)
+		}
+	}
+}
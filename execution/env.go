@@ -0,0 +1,147 @@
+package execution
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/subosito/gotenv"
+
+	"github.com/carrchang/handy-ci/config"
+)
+
+const (
+	EnvWorkspace      = "HANDY_CI_WORKSPACE"
+	EnvGroup          = "HANDY_CI_GROUP"
+	EnvRepository     = "HANDY_CI_REPOSITORY"
+	EnvRepositoryPath = "HANDY_CI_REPOSITORY_PATH"
+)
+
+// Environment returns the variables added to the parent environment of executions in repository.
+//
+// Variables are taken from script definition, workspace, group, repository and script in order, a later
+// level overrides an earlier one. In each level, variables of envFile are overridden by variables of env.
+// Standard variables describing the repository are always set. Pass an empty scriptName for executions
+// not bound to a script.
+func Environment(
+	workspace config.Workspace, group config.Group, repository config.Repository, scriptName string) ([]string, error) {
+	repositoryPath := RepositoryPath(workspace, group, repository)
+
+	env := map[string]string{}
+
+	if scriptName != "" {
+		if scriptDefinition, defined := findScriptDefinition(scriptName); defined {
+			err := mergeEnv(env, scriptDefinition.EnvFile, scriptDefinition.Env, repositoryPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err := mergeEnv(env, workspace.EnvFile, workspace.Env, WorkspacePath(workspace))
+	if err != nil {
+		return nil, err
+	}
+
+	// Inherited env files are left out like the env file of their group while its directory doesn't exist.
+	for _, envFile := range group.InheritedEnvFiles {
+		dir := WorkspacePath(workspace)
+		if !filepath.IsAbs(envFile) {
+			dir, envFile = filepath.Join(dir, filepath.Dir(envFile)), filepath.Base(envFile)
+		}
+
+		err = mergeEnv(env, envFile, nil, dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = mergeEnv(env, group.EnvFile, group.Env, GroupPath(workspace, group))
+	if err != nil {
+		return nil, err
+	}
+
+	err = mergeEnv(env, repository.EnvFile, repository.Env, repositoryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if scriptName != "" {
+		for _, script := range repository.Scripts {
+			if script.Name == scriptName {
+				err = mergeEnv(env, script.EnvFile, script.Env, repositoryPath)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	env[EnvWorkspace] = workspace.Name
+	env[EnvGroup] = group.Name
+	env[EnvRepository] = repository.Name
+	env[EnvRepositoryPath] = repositoryPath
+
+	var keys []string
+	for key := range env {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var variables []string
+	for _, key := range keys {
+		variables = append(variables, key+"="+env[key])
+	}
+
+	return variables, nil
+}
+
+// mergeEnv merges variables of envFile and vars into env. A relative envFile is resolved against dir, it's
+// left out while dir doesn't exist, such as a repository not cloned yet. Values may refer to variables
+// merged before or to the parent environment.
+func mergeEnv(env map[string]string, envFile string, vars map[string]string, dir string) error {
+	lookup := func(key string) string {
+		if value, ok := env[key]; ok {
+			return value
+		}
+
+		return os.Getenv(key)
+	}
+
+	if envFile != "" {
+		envFile = os.ExpandEnv(envFile)
+
+		if !filepath.IsAbs(envFile) {
+			if _, err := os.Stat(dir); os.IsNotExist(err) {
+				envFile = ""
+			} else {
+				envFile = filepath.Join(dir, envFile)
+			}
+		}
+	}
+
+	if envFile != "" {
+		fileVars, err := gotenv.Read(envFile)
+		if err != nil {
+			return ParseError{"Unable to read env file " + envFile + ", " + err.Error()}
+		}
+
+		for key, value := range fileVars {
+			env[key] = value
+		}
+	}
+
+	var keys []string
+	for key := range vars {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		env[key] = os.Expand(vars[key], lookup)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package execution
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/carrchang/handy-ci/config"
+)
+
+func envValue(env []string, key string) (string, bool) {
+	for _, variable := range env {
+		if len(variable) > len(key) && variable[:len(key)+1] == key+"=" {
+			return variable[len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
+func TestEnvironment_Precedence(t *testing.T) {
+	config.HandyCiConfig = &config.Config{ScriptDefinitions: []config.ScriptDefinition{
+		{Name: "mvn", Env: map[string]string{"MAVEN_OPTS": "-Xmx1g", "JAVA_HOME": "/jdk8", "NODE_ENV": "development"}},
+	}}
+	workspace := config.Workspace{Name: "ws", Path: "/base", Env: map[string]string{"JAVA_HOME": "/jdk11"}}
+	group := config.Group{Name: "grp", Env: map[string]string{"JAVA_HOME": "/jdk17", "NODE_ENV": "test"}}
+	repository := config.Repository{
+		Name:    "repo",
+		Env:     map[string]string{"NODE_ENV": "production"},
+		Scripts: []config.Script{{Name: "mvn", Env: map[string]string{"MAVEN_OPTS": "$MAVEN_OPTS -Dquiet"}}},
+	}
+
+	env, err := Environment(workspace, group, repository, "mvn")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"MAVEN_OPTS":      "-Xmx1g -Dquiet",
+		"JAVA_HOME":       "/jdk17",
+		"NODE_ENV":        "production",
+		EnvWorkspace:      "ws",
+		EnvGroup:          "grp",
+		EnvRepository:     "repo",
+		EnvRepositoryPath: RepositoryPath(workspace, group, repository),
+	}
+	for key, value := range expected {
+		if got, _ := envValue(env, key); got != value {
+			t.Fatalf("expected %s=%s, got %s in %v", key, value, got, env)
+		}
+	}
+
+	env, err = Environment(workspace, group, repository, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := envValue(env, "MAVEN_OPTS"); ok {
+		t.Fatalf("expected no script variables without script, got %v", env)
+	}
+}
+
+func TestEnvironment_EnvFile(t *testing.T) {
+	config.HandyCiConfig = &config.Config{}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "grp", "repo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "grp", "repo", ".env"), []byte("JAVA_HOME=/from-file\nNODE_ENV=file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	workspace := config.Workspace{Name: "ws", Path: dir}
+	group := config.Group{Name: "grp"}
+	repository := config.Repository{Name: "repo", EnvFile: ".env", Env: map[string]string{"NODE_ENV": "production"}}
+
+	env, err := Environment(workspace, group, repository, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := envValue(env, "JAVA_HOME"); got != "/from-file" {
+		t.Fatalf("expected JAVA_HOME from env file, got %v", env)
+	}
+	if got, _ := envValue(env, "NODE_ENV"); got != "production" {
+		t.Fatalf("expected env to override env file, got %v", env)
+	}
+
+	repository.EnvFile = "missing.env"
+	if _, err := Environment(workspace, group, repository, ""); err == nil {
+		t.Fatalf("expected error for missing env file")
+	}
+}
+
+func TestEnvironment_EnvFileOfRepositoryNotCloned(t *testing.T) {
+	config.HandyCiConfig = &config.Config{}
+
+	workspace := config.Workspace{Name: "ws", Path: t.TempDir()}
+	group := config.Group{Name: "grp"}
+	repository := config.Repository{Name: "repo", EnvFile: ".env", Env: map[string]string{"NODE_ENV": "production"}}
+
+	env, err := Environment(workspace, group, repository, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := envValue(env, "NODE_ENV"); got != "production" {
+		t.Fatalf("expected env without env file, got %v", env)
+	}
+
+	repository.Remotes = []config.GitRemote{{Name: "origin", URL: "git@gitlab.com:keepnative/repo.git"}}
+	if _, err := (GitExecution{}).Parse(&cobra.Command{Use: "git"}, []string{"clone"}, workspace, group, repository); err != nil {
+		t.Fatalf("unexpected error for clone: %v", err)
+	}
+}
+
+func TestEnvironment_EnvFilesOfNestedGroups(t *testing.T) {
+	config.HandyCiConfig = &config.Config{}
+	dir := t.TempDir()
+	files := map[string]string{
+		filepath.Join(dir, "top", ".env"):                "JAVA_HOME=/jdk8\nNODE_ENV=top\nMAVEN_OPTS=-Xmx1g\n",
+		filepath.Join(dir, "top", "mid", ".env"):         "JAVA_HOME=/jdk11\nNODE_ENV=mid\n",
+		filepath.Join(dir, "top", "mid", "leaf", ".env"): "NODE_ENV=leaf\n",
+	}
+	for file, content := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handyCiConfig := &config.Config{Workspaces: []config.Workspace{{Name: "ws", Path: dir, Groups: []config.Group{
+		{Name: "top", EnvFile: ".env", Groups: []config.Group{
+			{Name: "mid", EnvFile: ".env", Groups: []config.Group{
+				{Name: "leaf", EnvFile: ".env", Repositories: []config.Repository{{Name: "repo"}}},
+			}},
+		}},
+	}}}}
+	if err := handyCiConfig.Resolve(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	workspace := handyCiConfig.Workspaces[0]
+	leaf := workspace.Groups[2]
+
+	env, err := Environment(workspace, leaf, leaf.Repositories[0], "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{"MAVEN_OPTS": "-Xmx1g", "JAVA_HOME": "/jdk11", "NODE_ENV": "leaf"}
+	for key, value := range expected {
+		if got, _ := envValue(env, key); got != value {
+			t.Fatalf("expected %s=%s, got %s in %v", key, value, got, env)
+		}
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"github.com/carrchang/handy-ci/util"
 	"github.com/spf13/cobra"
 	"os"
+	"regexp"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/carrchang/handy-ci/config"
 )
@@ -79,18 +82,10 @@ func (s ExecExecution) Parse(
 								fmt.Sprintf("%s"+string(os.PathSeparator)+"%s", repositoryPath, strings.Trim(path, string(os.PathSeparator))),
 								string(os.PathSeparator))
 
-							executions = append(executions, Execution{
-								Command: currentScript,
-								Path:    executionPath,
-								Args:    executionArgs,
-							})
+							executions = append(executions, scriptExecutions(currentScript, executionPath, executionArgs)...)
 						}
 					} else {
-						executions = append(executions, Execution{
-							Command: currentScript,
-							Path:    repositoryPath,
-							Args:    executionArgs,
-						})
+						executions = append(executions, scriptExecutions(currentScript, repositoryPath, executionArgs)...)
 					}
 
 					matched = true
@@ -101,11 +96,7 @@ func (s ExecExecution) Parse(
 		}
 
 		if nonStrict && !matched {
-			executions = append(executions, Execution{
-				Command: currentScript,
-				Path:    repositoryPath,
-				Args:    executionArgs,
-			})
+			executions = append(executions, scriptExecutions(currentScript, repositoryPath, executionArgs)...)
 		}
 	} else {
 		if len(repository.Scripts) > 0 {
@@ -117,28 +108,207 @@ func (s ExecExecution) Parse(
 				}
 			}
 
-			for _, scriptDefinition := range ScriptDefinitions() {
-				if scriptDefinition.Name == currentScript {
-					if scriptDefinition.DefaultArgs != "" {
-						var args = strings.Split(scriptDefinition.DefaultArgs, " ")
-
-						for _, arg := range args {
-							trimmed := strings.Trim(arg, " ")
-							if trimmed != "" {
-								executionArgs = append(executionArgs, trimmed)
-							}
+			if scriptDefinition, defined := findScriptDefinition(currentScript); defined {
+				if scriptDefinition.DefaultArgs != "" {
+					var args = strings.Split(scriptDefinition.DefaultArgs, " ")
+
+					for _, arg := range args {
+						trimmed := strings.Trim(arg, " ")
+						if trimmed != "" {
+							executionArgs = append(executionArgs, trimmed)
 						}
 					}
 				}
 			}
 
-			executions = append(executions, Execution{
-				Command: currentScript,
-				Path:    repositoryPath,
-				Args:    executionArgs,
-			})
+			executions = append(executions, scriptExecutions(currentScript, repositoryPath, executionArgs)...)
+		}
+	}
+
+	if len(executions) > 0 {
+		env, err := Environment(workspace, group, repository, currentScript)
+		if err != nil {
+			return nil, err
+		}
+
+		timeout, err := scriptTimeout(repository, currentScript)
+		if err != nil {
+			return nil, err
+		}
+
+		retry, err := scriptRetry(currentScript)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range executions {
+			executions[i].Env = env
+			executions[i].Timeout = timeout
+			executions[i].Retry = retry
+			executions[i].Script = currentScript
 		}
 	}
 
 	return executions, nil
 }
+
+// scriptTimeout returns the timeout of script in repository, which overrides the timeout of its script
+// definition. Zero means no timeout.
+func scriptTimeout(repository config.Repository, scriptName string) (time.Duration, error) {
+	var timeout string
+
+	if scriptDefinition, defined := findScriptDefinition(scriptName); defined {
+		timeout = scriptDefinition.Timeout
+	}
+
+	for _, script := range repository.Scripts {
+		if script.Name == scriptName && script.Timeout != "" {
+			timeout = script.Timeout
+		}
+	}
+
+	if timeout == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, ParseError{"Invalid timeout of script " + scriptName + ", " + err.Error()}
+	}
+
+	return duration, nil
+}
+
+func findScriptDefinition(scriptName string) (config.ScriptDefinition, bool) {
+	for _, scriptDefinition := range ScriptDefinitions() {
+		if scriptDefinition.Name == scriptName {
+			return scriptDefinition, true
+		}
+	}
+
+	return config.ScriptDefinition{}, false
+}
+
+// scriptExecutions expands a script into its pre steps, main steps and post steps. A script without a
+// definition, or with a definition without steps, runs as a single command.
+func scriptExecutions(scriptName string, path string, args []string) []Execution {
+	scriptDefinition, defined := findScriptDefinition(scriptName)
+
+	if !defined {
+		return []Execution{
+			{
+				Command: scriptName,
+				Path:    path,
+				Args:    args,
+			},
+		}
+	}
+
+	var executions []Execution
+
+	executions = append(executions, stepExecutions("pre", scriptDefinition.Pre, scriptDefinition.Shell, path, nil)...)
+
+	if len(scriptDefinition.Steps) > 0 {
+		executions = append(executions, stepExecutions("step", scriptDefinition.Steps, scriptDefinition.Shell, path, args)...)
+	} else if scriptDefinition.Shell {
+		command, shellArgs := shellCommand(strings.Join(append([]string{scriptName}, args...), " "), scriptName, nil)
+
+		executions = append(executions, Execution{
+			Command: command,
+			Path:    path,
+			Args:    shellArgs,
+		})
+	} else {
+		executions = append(executions, Execution{
+			Command: scriptName,
+			Path:    path,
+			Args:    args,
+		})
+	}
+
+	executions = append(executions, stepExecutions("post", scriptDefinition.Post, scriptDefinition.Shell, path, nil)...)
+
+	return executions
+}
+
+// stepExecutions converts steps to executions. Arguments are appended to plain steps and passed as
+// positional parameters to shell steps.
+func stepExecutions(kind string, steps []config.Step, shell bool, path string, args []string) []Execution {
+	var executions []Execution
+
+	for i, step := range steps {
+		stepName := step.Name
+		if stepName == "" {
+			stepName = fmt.Sprintf("%s #%d", kind, i+1)
+		}
+
+		var command string
+		var commandArgs []string
+
+		if shell || step.Shell {
+			if strings.TrimSpace(step.Run) == "" {
+				continue
+			}
+
+			command, commandArgs = shellCommand(step.Run, stepName, args)
+		} else {
+			fields := strings.Fields(step.Run)
+			if len(fields) == 0 {
+				continue
+			}
+
+			command = fields[0]
+			commandArgs = append(commandArgs, fields[1:]...)
+			commandArgs = append(commandArgs, args...)
+		}
+
+		executions = append(executions, Execution{
+			Command:      command,
+			Path:         path,
+			Args:         commandArgs,
+			Step:         stepName,
+			AllowFailure: step.AllowFailure,
+		})
+	}
+
+	return executions
+}
+
+// shellCommand returns the command and arguments running script through the system shell.
+func shellCommand(script string, name string, args []string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", strings.Join(append([]string{script}, args...), " ")}
+	}
+
+	return "sh", append([]string{"-c", script, name}, args...)
+}
+
+// scriptRetry returns the retry of the script definition of script.
+func scriptRetry(scriptName string) (Retry, error) {
+	scriptDefinition, defined := findScriptDefinition(scriptName)
+	if !defined {
+		return Retry{}, nil
+	}
+
+	retry := Retry{Attempts: scriptDefinition.Retry.Attempts, OnExitCodes: scriptDefinition.Retry.OnExitCodes}
+
+	if scriptDefinition.Retry.Backoff != "" {
+		backoff, err := time.ParseDuration(scriptDefinition.Retry.Backoff)
+		if err != nil {
+			return Retry{}, ParseError{"Invalid retry backoff of script " + scriptName + ", " + err.Error()}
+		}
+
+		retry.Backoff = backoff
+	}
+
+	if scriptDefinition.Retry.OnOutputMatch != "" {
+		onOutputMatch, err := regexp.Compile(scriptDefinition.Retry.OnOutputMatch)
+		if err != nil {
+			return Retry{}, ParseError{"Invalid retry onOutputMatch of script " + scriptName + ", " + err.Error()}
+		}
+
+		retry.OnOutputMatch = onOutputMatch
+	}
+
+	return retry, nil
+}
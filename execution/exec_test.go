@@ -2,6 +2,7 @@ package execution
 
 import (
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -112,3 +113,89 @@ func TestExecExecution_Parse_NonStrictUnknownScript(t *testing.T) {
 		t.Fatalf("unexpected execution: %+v", executions[0])
 	}
 }
+
+func TestExecExecution_Parse_MultiStepScript(t *testing.T) {
+	config.HandyCiConfig = &config.Config{ScriptDefinitions: []config.ScriptDefinition{{
+		Name:  "build",
+		Pre:   []config.Step{{Name: "prepare", Run: "mvn -v"}},
+		Steps: []config.Step{{Run: "mvn -q clean install"}, {Run: "npm ci && npm run build", Shell: true}},
+		Post:  []config.Step{{Run: "echo done", AllowFailure: true}},
+	}}}
+	workspace := config.Workspace{Name: "ws", Path: "/root"}
+	group := config.Group{Name: "grp"}
+	repo := config.Repository{Name: "repo", Scripts: []config.Script{{Name: "build"}}}
+
+	executions, err := ExecExecution{}.Parse(newExecCommand(), []string{"build"}, workspace, group, repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(executions) != 4 {
+		t.Fatalf("expected 4 executions, got %d: %+v", len(executions), executions)
+	}
+	if executions[0].Step != "prepare" || executions[0].Command != "mvn" {
+		t.Fatalf("unexpected pre step: %+v", executions[0])
+	}
+	if executions[1].Step != "step #1" || executions[1].Command != "mvn" || len(executions[1].Args) != 3 {
+		t.Fatalf("unexpected first step: %+v", executions[1])
+	}
+	if executions[2].Command != "sh" || executions[2].Args[0] != "-c" || executions[2].Args[1] != "npm ci && npm run build" {
+		t.Fatalf("unexpected shell step: %+v", executions[2])
+	}
+	if !executions[3].AllowFailure || executions[3].Step != "post #1" {
+		t.Fatalf("unexpected post step: %+v", executions[3])
+	}
+}
+
+func TestExecExecution_Parse_ShellScriptWithoutSteps(t *testing.T) {
+	config.HandyCiConfig = &config.Config{ScriptDefinitions: []config.ScriptDefinition{{Name: "mvn", Shell: true}}}
+	workspace := config.Workspace{Name: "ws", Path: "/root"}
+	group := config.Group{Name: "grp"}
+	repo := config.Repository{Name: "repo", Scripts: []config.Script{{Name: "mvn"}}}
+
+	executions, err := ExecExecution{}.Parse(newExecCommand(), []string{"mvn", "clean", "|", "tee", "out.log"}, workspace, group, repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(executions) != 1 || executions[0].Command != "sh" || executions[0].Args[1] != "mvn clean | tee out.log" {
+		t.Fatalf("unexpected executions: %+v", executions)
+	}
+}
+
+func TestExecExecution_Parse_ScriptTimeoutOverridesDefinition(t *testing.T) {
+	config.HandyCiConfig = &config.Config{ScriptDefinitions: []config.ScriptDefinition{{Name: "mvn", Timeout: "10m"}}}
+	workspace := config.Workspace{Name: "ws", Path: "/root"}
+	group := config.Group{Name: "grp"}
+	cmd := newExecCommand()
+
+	executions, err := ExecExecution{}.Parse(
+		cmd, []string{"mvn"}, workspace, group, config.Repository{Name: "repo", Scripts: []config.Script{{Name: "mvn"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if executions[0].Timeout != 10*time.Minute {
+		t.Fatalf("expected timeout of definition, got %v", executions[0].Timeout)
+	}
+
+	executions, err = ExecExecution{}.Parse(
+		cmd, []string{"mvn"}, workspace, group,
+		config.Repository{Name: "repo", Scripts: []config.Script{{Name: "mvn", Timeout: "1h30m"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if executions[0].Timeout != 90*time.Minute {
+		t.Fatalf("expected timeout of repository script, got %v", executions[0].Timeout)
+	}
+}
+
+func TestExecExecution_Parse_InvalidTimeout(t *testing.T) {
+	config.HandyCiConfig = &config.Config{ScriptDefinitions: []config.ScriptDefinition{{Name: "mvn", Timeout: "soon"}}}
+	workspace := config.Workspace{Name: "ws", Path: "/root"}
+	group := config.Group{Name: "grp"}
+
+	repo := config.Repository{Name: "repo", Scripts: []config.Script{{Name: "mvn"}}}
+
+	_, err := ExecExecution{}.Parse(newExecCommand(), []string{"mvn"}, workspace, group, repo)
+	if _, ok := err.(ParseError); !ok {
+		t.Fatalf("expected ParseError, got %v", err)
+	}
+}
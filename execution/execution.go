@@ -5,6 +5,7 @@ import (
   "os"
   "path/filepath"
   "strings"
+  "time"
 
   "github.com/spf13/cobra"
 
@@ -12,12 +13,29 @@ import (
 )
 
 type Execution struct {
-  Command string
-  Path    string
-  Args    []string
-  Skip    bool
+  Command      string
+  Script       string
+  Path         string
+  Args         []string
+  Skip         bool
+  Step         string
+  AllowFailure bool
+  Env          []string
+  Timeout      time.Duration
+  Retry        Retry
+  Status       Status
 }
 
+type Status string
+
+const (
+  StatusPending   Status = ""
+  StatusSucceeded Status = "succeeded"
+  StatusFailed    Status = "failed"
+  StatusSkipped   Status = "skipped"
+  StatusTimedOut  Status = "timed-out"
+)
+
 type Parser interface {
   CheckArgs(command *cobra.Command, args []string) error
 
@@ -34,7 +52,22 @@ func (e ParseError) Error() string {
   return fmt.Sprintf("%s", e.message)
 }
 
-func GroupPath(workspace config.Workspace, group config.Group) string {
+// TimeoutError is returned by executions stopped because they took longer than their timeout, or than the
+// deadline of the run.
+type TimeoutError struct {
+  message string
+}
+
+func (e TimeoutError) Error() string {
+  return e.message
+}
+
+// ExitCode returns the exit code of Handy CI stopped by a timeout, the exit code of timeout command.
+func (e TimeoutError) ExitCode() int {
+  return 124
+}
+
+func WorkspacePath(workspace config.Workspace) string {
   workspacePath := filepath.FromSlash(workspace.Path)
 
   workspacePath = strings.ReplaceAll(workspacePath, "$HANDY_CI_ROOT", os.Getenv("HANDY_CI_ROOT"))
@@ -45,7 +78,11 @@ func GroupPath(workspace config.Workspace, group config.Group) string {
     workspacePath = strings.ReplaceAll(workspacePath, "$HOME", homeDir)
   }
 
-  workspacePath = strings.TrimSuffix(workspacePath, string(os.PathSeparator))
+  return strings.TrimSuffix(workspacePath, string(os.PathSeparator))
+}
+
+func GroupPath(workspace config.Workspace, group config.Group) string {
+  workspacePath := WorkspacePath(workspace)
 
   if len(group.Path) > 0 {
     if strings.HasPrefix(group.Path, string(os.PathSeparator)) {
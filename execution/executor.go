@@ -1,11 +1,16 @@
 package execution
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/logrusorgru/aurora"
 	"github.com/spf13/cobra"
@@ -15,418 +20,560 @@ import (
 	"github.com/carrchang/handy-ci/util"
 )
 
-func Execute(command *cobra.Command, args []string, executionParser Parser) {
+// Output formats of the result of a run.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Execute runs the command of executionParser in the selected repositories. The error returned tells the
+// run didn't succeed, its messages are already printed.
+func Execute(command *cobra.Command, args []string, executionParser Parser) error {
 	cleanedArgs, err := ParseFlagsAndArgs(command.Flags(), args)
 
 	if err != nil {
 		fmt.Printf("\n%v\n\n", err)
-		return
+		return err
 	}
 
 	err = executionParser.CheckArgs(command, cleanedArgs)
 
 	if err != nil {
 		fmt.Printf("\n%v\n\n", err)
-		return
+		return err
 	}
 
 	help, _ := command.Flags().GetBool("help")
 
 	if help {
 		command.Help()
-		return
+		return nil
 	}
 
-	execInWorkspaces(command, cleanedArgs, executionParser)
-}
+	state := &run{}
 
-func execInWorkspaces(command *cobra.Command, args []string, executionParser Parser) error {
-	currentWorkspace, _ := command.Flags().GetString(util.HandyCiFlagWorkspace)
-
-	for _, workspace := range Workspaces() {
-		if currentWorkspace != "" {
-			if workspace.Name == currentWorkspace {
-				err := execInGroups(command, args, executionParser, workspace)
+	failFast, failAtEnd, err := failureMode(command.Flags())
+	if err != nil {
+		fmt.Printf("\n%v\n\n", err)
+		return err
+	}
 
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			err := execInGroups(command, args, executionParser, workspace)
+	state.failAtEnd = failAtEnd
 
-			if err != nil {
-				return err
-			}
-		}
+	switch {
+	case failFast:
+		command.Flags().Set(util.HandyCiFlagContinue, "false")
+	case state.failAtEnd:
+		command.Flags().Set(util.HandyCiFlagContinue, "true")
 	}
 
-	return nil
-}
+	toBeContinue, _ := command.Flags().GetBool(util.HandyCiFlagContinue)
+	state.unattended = toBeContinue || failFast || failAtEnd
 
-func execInGroups(command *cobra.Command, args []string, executionParser Parser, workspace config.Workspace) error {
-	currentGroup, _ := command.Flags().GetString(util.HandyCiFlagGroup)
+	if deadline, _ := command.Flags().GetDuration(util.HandyCiFlagDeadline); deadline > 0 {
+		ctx, cancel := context.WithTimeout(commandContext(command), deadline)
+		defer cancel()
 
-	for _, group := range workspace.Groups {
-		if currentGroup != "" {
-			if group.Name == currentGroup {
-				err := execInRepositories(command, args, executionParser, workspace, group)
+		command.SetContext(ctx)
+	}
 
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			err := execInRepositories(command, args, executionParser, workspace, group)
+	runCommand := append([]string{command.Name()}, cleanedArgs...)
 
-			if err != nil {
-				return err
-			}
-		}
-	}
+	var selection []string
 
-	return nil
-}
+	for _, target := range Selection(command) {
+		state.labelWidth = max(state.labelWidth, len(target.Group.Name+"/"+target.Repository.Name))
 
-func execInRepositories(
-	command *cobra.Command, args []string, executionParser Parser, workspace config.Workspace, group config.Group) error {
-	var targetRepositories []string
-	targetRepositoriesInString, _ := command.Flags().GetString(util.HandyCiFlagRepositories)
-	if targetRepositoriesInString != "" {
-		for _, targetRepository := range strings.Split(targetRepositoriesInString, ",") {
-			trimmed := strings.Trim(targetRepository, " ")
-			if trimmed != "" {
-				targetRepositories = append(targetRepositories, trimmed)
-			}
-		}
+		selection = append(selection, progressKey(target.Workspace.Name, target.Group.Name, target.Repository.Name))
 	}
 
-	var tagsAsArgument []string
-	tagsAsArgumentInString, _ := command.Flags().GetString(util.HandyCiFlagTags)
-	if tagsAsArgumentInString != "" {
-		for _, tagAsArgumentInString := range strings.Split(tagsAsArgumentInString, ",") {
-			trimmed := strings.Trim(tagAsArgumentInString, " ")
-			if trimmed != "" {
-				tagsAsArgument = append(tagsAsArgument, trimmed)
-			}
+	if showIf, _ := command.Flags().GetString(util.HandyCiFlagShowIf); showIf != "" {
+		state.showIf, err = regexp.Compile(showIf)
+		if err != nil {
+			fmt.Printf("\nInvalid --%s, %v\n\n", util.HandyCiFlagShowIf, err)
+			return err
 		}
+
+		command.Flags().Set(util.HandyCiFlagQuiet, "true")
 	}
 
-	toBeContinue, _ := command.Flags().GetBool(util.HandyCiFlagContinue)
-	fromRepository, _ := command.Flags().GetString(util.HandyCiFlagFrom)
-
-	var skippedRepositories []string
-	skippedRepositoriesInString, _ := command.Flags().GetString(util.HandyCiFlagSkip)
-	if skippedRepositoriesInString != "" {
-		for _, skippedRepository := range strings.Split(skippedRepositoriesInString, ",") {
-			trimmed := strings.Trim(skippedRepository, " ")
-			if trimmed != "" {
-				skippedRepositories = append(skippedRepositories, trimmed)
-			}
-		}
+	if state.interactive = isInteractive(command); state.interactive {
+		state.answers = readStdinLine
 	}
 
 	dryRun, _ := command.Flags().GetBool(util.HandyCiFlagDryRun)
+	keepLogs, _ := command.Flags().GetInt(util.HandyCiFlagKeepLogs)
+
+	output, _ := command.Flags().GetString(util.HandyCiFlagOutput)
+
+	switch output {
+	case "", OutputText:
+	case OutputJSON:
+		// Standard output is kept for the result of the run, what commands and Handy CI print goes to
+		// standard error instead.
+		stdout, started := os.Stdout, time.Now()
+		os.Stdout = os.Stderr
+
+		defer func() {
+			os.Stdout = stdout
+			state.writeResult(stdout, os.Args[1:], runCommand, selection, started)
+		}()
+	default:
+		err = ParseError{fmt.Sprintf("Invalid --%s %s, one of %s, %s", util.HandyCiFlagOutput, output, OutputText, OutputJSON)}
+		fmt.Printf("\n%v\n\n", err)
+		return err
+	}
 
-	var resume bool
-
-	for _, repository := range group.Repositories {
-		if !resume && fromRepository != "" {
-			if strings.EqualFold(repository.Name, fromRepository) {
-				resume = true
-			} else {
-				continue
-			}
+	if !dryRun && keepLogs > 0 {
+		log, err := newRunLog(LogsDirectory(), os.Args[1:], keepLogs)
+		if err != nil {
+			util.Eprintf("Unable to write logs, %v\n", err)
+		} else {
+			log.manifest.Command, log.manifest.Selection = runCommand, selection
 		}
 
-		if util.ContainArgs(skippedRepositories, repository.Name) {
-			continue
-		}
+		state.log = log
+		defer state.closeLog()
+	}
 
-		if !repositoryTagsContainAllTagsAsArgument(repository, tagsAsArgument) {
-			continue
-		}
+	if keepHistory, _ := command.Flags().GetInt(util.HandyCiFlagKeepHistory); !dryRun && keepHistory > 0 {
+		state.history, state.keepHistory = HistoryFile(), keepHistory
 
-		if targetRepositoriesInString != "" {
-			if util.ContainArgs(targetRepositories, repository.Name) {
-				i, err := execInRepository(command, args, executionParser, workspace, group, repository, toBeContinue, dryRun)
+		started := time.Now()
+		defer state.recordHistory(os.Args[1:], runCommand, selection, started)
+	}
 
-				if err != nil && !toBeContinue {
-					return err
-				}
+	if showProgress, _ := command.Flags().GetBool(util.HandyCiFlagProgress); showProgress && !dryRun &&
+		output != OutputJSON && util.IsTerminal(os.Stdout) {
+		state.progress = newProgress(os.Stdout, func() int { return terminalWidth(os.Stdout) }, selection,
+			PreviousDurations(PreviousRuns(HistoryFile(), LogsDirectory()), runCommand, selection))
+		state.progress.start()
+		defer state.progress.close()
 
-				if i > 0 {
-					util.Println()
-				}
-			}
-		} else {
-			i, err := execInRepository(command, args, executionParser, workspace, group, repository, toBeContinue, dryRun)
+		// Output of repositories is collapsed into the progress, and shown when they fail.
+		command.Flags().Set(util.HandyCiFlagQuiet, "true")
+	}
 
-			if err != nil && !toBeContinue {
-				return err
-			}
+	ctx, stopNotify := notifyInterrupts(withRun(commandContext(command), state), state)
+	defer stopNotify()
 
-			if i > 0 {
-				util.Println()
-			}
-		}
-	}
+	command.SetContext(ctx)
 
-	return nil
-}
+	err = execInWorkspaces(command, cleanedArgs, executionParser)
 
-func repositoryTagsContainAllTagsAsArgument(repository config.Repository, tagsAsArgument []string) bool {
-	for _, tagAsArgument := range tagsAsArgument {
-		if tagAsArgument != "" && !util.ContainArgs(repository.Tags, tagAsArgument) {
-			return false
-		}
+	if state.interrupted() {
+		printInterruptSummary(state)
+
+		return InterruptError{state.signal}
 	}
 
-	return true
-}
+	failed, skipped := state.failures()
 
-func execInRepository(
-	command *cobra.Command, args []string, executionParser Parser,
-	workspace config.Workspace, group config.Group, repository config.Repository, toBeContinue bool, dryRun bool) (int, error) {
-	util.Printf("PATH: %s\n", repository.Name)
-	executions, err := executionParser.Parse(command, args, workspace, group, repository)
-
-	if err != nil && !toBeContinue {
-		util.Printf("%v\n", err.Error())
-		return 0, err
+	if (toBeContinue || failFast) && len(failed) > 0 {
+		printFailureSummary(failed, skipped)
 	}
 
-	for i, execution := range executions {
+	if _, deadlineExceeded := err.(TimeoutError); deadlineExceeded && toBeContinue {
+		return err
+	}
 
-		util.Printf("SCRIPT: %s %s\n", execution.Command, strings.Join(execution.Args, " "))
-		util.Printf("PATH: %s\n", execution.Path)
+	if state.isAborted() {
+		return err
+	}
 
-		if dryRun {
-			continue
-		}
+	if state.failAtEnd && len(failed) > 0 {
+		return fmt.Errorf("%d repositories failed", len(failed))
+	}
 
-		if execution.Skip {
-			continue
-		}
+	// Failures are ignored with --continue.
+	if toBeContinue {
+		return nil
+	}
 
-		util.Printf("%s\n", ">>>>>>")
+	return err
+}
 
-		executionCommand := exec.Command(execution.Command, execution.Args...)
-		executionCommand.Dir = execution.Path
-		executionCommand.Stdin = os.Stdin
-		executionCommand.Stdout = os.Stdout
-		executionCommand.Stderr = os.Stderr
+// failureMode returns whether --fail-fast or --fail-at-end is in effect. A mode given on command line
+// overrides the other one and --continue taken from defaults, so does --continue over a defaulted
+// --fail-fast. Both modes given on command line are an error.
+func failureMode(flags *pflag.FlagSet) (bool, bool, error) {
+	failFast, _ := flags.GetBool(util.HandyCiFlagFailFast)
+	failAtEnd, _ := flags.GetBool(util.HandyCiFlagFailAtEnd)
+	toBeContinue, _ := flags.GetBool(util.HandyCiFlagContinue)
+
+	given := func(name string) bool {
+		flag := flags.Lookup(name)
+		return flag != nil && flag.Changed
+	}
 
-		err := executionCommand.Run()
-		if err != nil && !toBeContinue {
-			fmt.Printf("%v\n", err)
-			util.Printf("%s\n", "<<<<<<")
-			return i, err
+	switch {
+	case failFast && failAtEnd && given(util.HandyCiFlagFailFast) == given(util.HandyCiFlagFailAtEnd):
+		return false, false, ParseError{
+			fmt.Sprintf("--%s and --%s can't be used together", util.HandyCiFlagFailFast, util.HandyCiFlagFailAtEnd),
 		}
+	case failFast && failAtEnd:
+		return given(util.HandyCiFlagFailFast), given(util.HandyCiFlagFailAtEnd), nil
+	case failFast && toBeContinue && !given(util.HandyCiFlagFailFast) && given(util.HandyCiFlagContinue):
+		return false, false, nil
+	}
 
-		util.Printf("%s\n", "<<<<<<")
+	return failFast, failAtEnd, nil
+}
 
-		if i < len(executions)-1 {
-			fmt.Println()
+func execInWorkspaces(command *cobra.Command, args []string, executionParser Parser) error {
+	for _, workspace := range selectedWorkspaces(command) {
+		err := execInGroups(command, args, executionParser, workspace)
+
+		if err != nil {
+			return err
 		}
 	}
 
-	return len(executions), nil
+	return nil
 }
 
-func ScriptDefinitions() []config.ScriptDefinition {
-	return config.HandyCiConfig.ScriptDefinitions
+func execInGroups(command *cobra.Command, args []string, executionParser Parser, workspace config.Workspace) error {
+	var targets []Target
+
+	for _, group := range selectedGroups(command, workspace) {
+		targets = append(targets, groupTargets(command, workspace, group)...)
+	}
+
+	return execInTargets(command, args, executionParser, targets)
 }
 
-func Workspaces() []config.Workspace {
-	return config.HandyCiConfig.Workspaces
+func execInRepositories(
+	command *cobra.Command, args []string, executionParser Parser, workspace config.Workspace, group config.Group) error {
+	return execInTargets(command, args, executionParser, groupTargets(command, workspace, group))
 }
 
-func ParseFlagsAndArgs(flags *pflag.FlagSet, args []string) ([]string, error) {
-	var cleanedArgs []string
+// execInTargets runs the command in the repositories of targets, each after the repositories it depends on.
+func execInTargets(command *cobra.Command, args []string, executionParser Parser, targets []Target) error {
+	toBeContinue, _ := command.Flags().GetBool(util.HandyCiFlagContinue)
+	dryRun, _ := command.Flags().GetBool(util.HandyCiFlagDryRun)
 
-	for i := 0; i < len(args); i++ {
-		if args[i] == "--"+util.HandyCiFlagWorkspace || args[i] == "-"+util.HandyCiFlagWorkspaceShorthand {
-			arg, err := parseFlagAndArg(args, i, args[i], true)
+	ctx := commandContext(command)
+	state := runOf(ctx)
 
-			if err != nil {
-				return cleanedArgs, err
-			}
+	targets, err := OrderByDependencies(targets)
+	if err != nil {
+		fmt.Printf("\n%v\n\n", err)
+		return err
+	}
 
-			flags.Set(util.HandyCiFlagWorkspace, arg)
+	for _, target := range targets {
+		workspace, group, repository := target.Workspace, target.Group, target.Repository
 
-			i++
+		if state.interrupted() {
+			state.stopBefore(repository.Name)
 
-			continue
+			return InterruptError{os.Interrupt}
 		}
 
-		if args[i] == "--"+util.HandyCiFlagGroup || args[i] == "-"+util.HandyCiFlagGroupShorthand {
-			arg, err := parseFlagAndArg(args, i, args[i], true)
+		if dependency := state.failedDependency(workspace, repository); dependency != "" && state.failAtEnd {
+			util.Printf("SKIPPED: %s depends on failed %s\n", repository.Name, dependency)
+			util.Println()
 
-			if err != nil {
-				return cleanedArgs, err
-			}
+			state.skipForFailure(
+				RepositoryResult{Workspace: workspace.Name, Group: group.Name, Repository: repository.Name}, dependency)
 
-			flags.Set(util.HandyCiFlagGroup, arg)
+			continue
+		}
 
-			i++
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			util.Printf("Deadline of run exceeded, %s not executed\n", repository.Name)
 
-			continue
+			return TimeoutError{"Deadline of run exceeded"}
 		}
 
-		if args[i] == "--"+util.HandyCiFlagRepositories || args[i] == "-"+util.HandyCiFlagRepositoriesShorthand {
-			arg, err := parseFlagAndArg(args, i, args[i], true)
+		if !dryRun {
+			result := RepositoryResult{Workspace: workspace.Name, Group: group.Name, Repository: repository.Name}
 
-			if err != nil {
-				return cleanedArgs, err
+			if state.history != "" {
+				result.Head = gitHead(RepositoryPath(workspace, group, repository))
 			}
 
-			flags.Set(util.HandyCiFlagRepositories, arg)
+			state.start(result)
+		}
 
-			i++
+		i, err := execInRepository(command, args, executionParser, workspace, group, repository, toBeContinue, dryRun)
 
-			continue
+		if !dryRun {
+			state.finish(resultStatus(err))
 		}
 
-		if args[i] == "--"+util.HandyCiFlagTags {
-			arg, err := parseFlagAndArg(args, i, args[i], true)
+		if err != nil && (!toBeContinue || state.isAborted()) {
+			return err
+		}
 
-			if err != nil {
-				return cleanedArgs, err
-			}
+		if i > 0 {
+			util.Println()
+		}
+	}
 
-			flags.Set(util.HandyCiFlagTags, arg)
+	return nil
+}
 
-			i++
+func execInRepository(
+	command *cobra.Command, args []string, executionParser Parser,
+	workspace config.Workspace, group config.Group, repository config.Repository, toBeContinue bool, dryRun bool) (int, error) {
+	state := runOf(commandContext(command))
 
-			continue
-		}
+	if quiet, _ := command.Flags().GetBool(util.HandyCiFlagQuiet); !quiet || dryRun {
+		return runInRepository(
+			command, args, executionParser, workspace, group, repository, toBeContinue, dryRun, os.Stdout, os.Stderr)
+	}
 
-		if args[i] == "--"+util.HandyCiFlagFrom || args[i] == "-"+util.HandyCiFlagFromShorthand {
-			arg, err := parseFlagAndArg(args, i, args[i], true)
+	// Quiet output of the repository is shown when it fails or matches --show-if.
+	output := &bytes.Buffer{}
+	started := time.Now()
 
-			if err != nil {
-				return cleanedArgs, err
-			}
+	i, err := runInRepository(
+		command, args, executionParser, workspace, group, repository, toBeContinue, dryRun, output, output)
 
-			flags.Set(util.HandyCiFlagFrom, arg)
+	if err != nil || state.showIf != nil && state.showIf.Match(output.Bytes()) {
+		output.WriteTo(os.Stdout)
+		return i, err
+	}
 
-			i++
+	util.Printf("%s/%s %s in %s\n",
+		group.Name, repository.Name, StatusSucceeded, time.Since(started).Round(time.Millisecond))
 
-			continue
-		}
+	// Nothing shown needs to be separated from the next repository.
+	return 0, err
+}
 
-		if args[i] == "--"+util.HandyCiFlagSkip {
-			arg, err := parseFlagAndArg(args, i, args[i], true)
+// runInRepository runs the executions of the command in repository, writing their framing and output to
+// stdout and stderr. Returns the number of executions run or the index of the execution failed first.
+func runInRepository(
+	command *cobra.Command, args []string, executionParser Parser,
+	workspace config.Workspace, group config.Group, repository config.Repository, toBeContinue bool, dryRun bool,
+	stdout io.Writer, stderr io.Writer) (int, error) {
+	util.Fprintf(stdout, "PATH: %s\n", repository.Name)
+	executions, err := executionParser.Parse(command, args, workspace, group, repository)
 
-			if err != nil {
-				return cleanedArgs, err
-			}
+	if err != nil {
+		util.Fprintf(stdout, "%v\n", err.Error())
+		return 0, err
+	}
 
-			flags.Set(util.HandyCiFlagSkip, arg)
+	ctx := commandContext(command)
+	state := runOf(ctx)
 
-			i++
+	var failedIndex int
+	var failed error
 
-			continue
-		}
+	for i := 0; i < len(executions); i++ {
+		execution := &executions[i]
 
-		if args[i] == "--"+util.HandyCiFlagContinue || args[i] == "-"+util.HandyCiFlagContinueShorthand {
-			arg, err := parseFlagAndArg(args, i, args[i], false)
+		if execution.Step != "" {
+			util.Fprintf(stdout, "STEP: %s\n", execution.Step)
+		}
 
-			if err != nil {
-				return cleanedArgs, err
-			}
+		util.Fprintf(stdout, "SCRIPT: %s %s\n", execution.Command, strings.Join(execution.Args, " "))
+		util.Fprintf(stdout, "PATH: %s\n", execution.Path)
 
-			flags.Set(util.HandyCiFlagContinue, arg)
+		if dryRun {
+			continue
+		}
 
+		if execution.Skip {
+			execution.Status = StatusSkipped
 			continue
 		}
 
-		if args[i] == "--"+util.HandyCiFlagConfig {
-			arg, err := parseFlagAndArg(args, i, args[i], true)
+		util.Fprintf(stdout, "%s\n", ">>>>>>")
 
-			if err != nil {
-				return cleanedArgs, err
-			}
+		if execution.Timeout == 0 {
+			execution.Timeout, _ = command.Flags().GetDuration(util.HandyCiFlagTimeout)
+		}
 
-			flags.Set(util.HandyCiFlagConfig, arg)
+		if execution.Retry.Attempts == 0 {
+			retries, _ := command.Flags().GetInt(util.HandyCiFlagRetry)
+			execution.Retry.Attempts = retries + 1
+		}
 
-			i++
+		executionStdout, executionStderr, flush := executionStreams(
+			command, workspace, group, repository, *execution, stdout, stderr)
 
-			continue
+		if state.progress != nil {
+			executionStdout = io.MultiWriter(executionStdout, state.progress)
+			executionStderr = io.MultiWriter(executionStderr, state.progress)
 		}
 
-		if args[i] == "--"+util.HandyCiFlagDryRun {
-			arg, err := parseFlagAndArg(args, i, args[i], false)
+		state.progress.startExecution(*execution)
 
-			if err != nil {
-				return cleanedArgs, err
-			}
+		started := time.Now()
+		err := runExecutionWithRetry(ctx, *execution, executionStdout, executionStderr)
 
-			flags.Set(util.HandyCiFlagDryRun, arg)
+		state.recordExecution(executionResult(*execution, err, time.Since(started)))
+		state.progress.finishExecution()
 
-			continue
-		}
+		flush()
+
+		if err != nil {
+			execution.Status = StatusFailed
+
+			if _, timedOut := err.(TimeoutError); timedOut {
+				execution.Status = StatusTimedOut
 
-		if args[i] == "--"+util.HandyCiFlagHelp {
-			arg, err := parseFlagAndArg(args, i, args[i], false)
+				util.Fprintf(stdout, "TIMED OUT: %v\n", err)
+			} else if _, interrupted := err.(InterruptError); interrupted {
+				execution.Status = StatusInterrupted
 
-			if err != nil {
-				return cleanedArgs, err
+				util.Fprintf(stdout, "INTERRUPTED: %v\n", err)
+			} else {
+				fmt.Fprintf(stdout, "%v\n", err)
 			}
 
-			flags.Set(util.HandyCiFlagHelp, arg)
+			util.Fprintf(stdout, "%s\n", "<<<<<<")
 
-			continue
-		}
+			// Without the prompt, the failure stops the repository and the run unless it continues.
+			choice := choiceAbort
+			if !execution.AllowFailure && state.promptsFailure(ctx, err) {
+				choice = state.resolveFailure(ctx, execution, stdout)
+			}
 
-		cleanedArgs = append(cleanedArgs, args[i])
-	}
+			switch {
+			case execution.AllowFailure:
+				util.Fprintf(stdout, "Failure of step %s allowed, continue\n", execution.Step)
+			case choice == choiceRetry:
+				fmt.Fprintln(stdout)
+				i--
+				continue
+			case choice == choiceSkip:
+				execution.Status = StatusSkipped
+				util.Fprintf(stdout, "Failure of step %s skipped, continue\n", execution.Step)
+			case toBeContinue && !state.isAborted() && ctx.Err() == nil:
+				// With --continue, only the rest of the steps of the failed script are skipped, the
+				// executions independent of it still run.
+				end := scriptEnd(executions, i)
+				skipRemainingExecutions(stdout, executions[i+1:end])
+
+				if failed == nil {
+					failedIndex, failed = i, err
+				}
 
-	return cleanedArgs, nil
-}
+				i = end - 1
+			default:
+				skipRemainingExecutions(stdout, executions[i+1:])
 
-func parseFlagAndArg(args []string, i int, flag string, withValue bool) (string, error) {
-	if withValue {
-		if len(args) == i+1 {
-			return "", ParseError{
-				"Value for flag " + flag + " is required, use \"handy-ci --help\" for more information.",
+				return i, err
 			}
 		} else {
-			return args[i+1], nil
+			execution.Status = StatusSucceeded
+
+			util.Fprintf(stdout, "%s\n", "<<<<<<")
+		}
+
+		if i < len(executions)-1 {
+			fmt.Fprintln(stdout)
 		}
 	}
 
-	return "true", nil
-}
+	if failed != nil {
+		return failedIndex, failed
+	}
 
-type executionWriter struct {
+	return len(executions), nil
 }
 
-func NewWriter() io.Writer {
-	return executionWriter{}
+// scriptEnd returns the index following the last step of the script run by the execution at i, in the same
+// path. Executions which aren't steps stand alone.
+func scriptEnd(executions []Execution, i int) int {
+	end := i + 1
+
+	if executions[i].Step == "" {
+		return end
+	}
+
+	for end < len(executions) && executions[end].Step != "" && executions[end].Path == executions[i].Path {
+		end++
+	}
+
+	return end
 }
 
-func (e executionWriter) Write(p []byte) (int, error) {
-	output := fmt.Sprintf("%s", p)
+// executionStreams returns the writers of the output of execution in repository to stdout and stderr, prefixed with the group
+// and name of repository with --prefix and copied to the log of execution unless they are a terminal, and a
+// function flushing them once the execution ends.
+func executionStreams(
+	command *cobra.Command, workspace config.Workspace, group config.Group, repository config.Repository,
+	execution Execution, stdout io.Writer, stderr io.Writer) (io.Writer, io.Writer, func()) {
+	var flushes []func()
 
-	if strings.Contains(output, "\n") {
-		outputArgs := strings.Split(output, "\n")
+	state := runOf(commandContext(command))
 
-		var realOutputArgs []interface{}
+	if prefix, _ := command.Flags().GetBool(util.HandyCiFlagPrefix); prefix {
+		label := prefixLabel(group.Name+"/"+repository.Name, state.labelWidth)
 
-		for i, outputArg := range outputArgs {
-			realOutputArgs = append(realOutputArgs, outputArg)
+		mutex := &sync.Mutex{}
+		prefixedStdout := newPrefixWriter(mutex, stdout, label)
+		prefixedStderr := newPrefixWriter(mutex, stderr, label)
 
-			if i != len(outputArgs)-1 {
-				realOutputArgs = append(realOutputArgs, "\n", aurora.Green("[Handy CI]"), " ")
-			}
+		stdout, stderr = prefixedStdout, prefixedStderr
+		flushes = append(flushes, func() {
+			prefixedStdout.Flush()
+			prefixedStderr.Flush()
+		})
+	}
+
+	if log := state.openLog(workspace, group, repository, execution); log != nil {
+		// Streams going to a terminal are given to the command as they are, so that it keeps its colors,
+		// progress bars and prompts, and aren't logged.
+		if terminalStream(stdout) && terminalStream(stderr) {
+			io.WriteString(log, "Output shown in the terminal, not logged\n")
+		}
+
+		if !terminalStream(stdout) {
+			stdout = io.MultiWriter(stdout, log)
+		}
+
+		if !terminalStream(stderr) {
+			stderr = io.MultiWriter(stderr, log)
 		}
 
-		fmt.Print(realOutputArgs...)
-	} else {
-		fmt.Print(output)
+		flushes = append(flushes, func() {
+			log.Close()
+		})
 	}
 
-	return len(p), nil
+	return stdout, stderr, func() {
+		for _, flush := range flushes {
+			flush()
+		}
+	}
+}
+
+// terminalStream tells whether stream is a terminal.
+func terminalStream(stream io.Writer) bool {
+	file, isFile := stream.(*os.File)
+
+	return isFile && util.IsTerminal(file)
+}
+
+// skipRemainingExecutions marks executions following a failed one as skipped.
+func skipRemainingExecutions(out io.Writer, executions []Execution) {
+	for i := range executions {
+		executions[i].Status = StatusSkipped
+
+		if executions[i].Step != "" {
+			util.Fprintf(out, "SKIPPED: %s\n", executions[i].Step)
+		} else {
+			util.Fprintf(out, "SKIPPED: %s %s\n", executions[i].Command, strings.Join(executions[i].Args, " "))
+		}
+	}
+}
+
+func ScriptDefinitions() []config.ScriptDefinition {
+	return config.HandyCiConfig.ScriptDefinitions
+}
+
+func Workspaces() []config.Workspace {
+	return config.HandyCiConfig.Workspaces
+}
+
+// NewWriter returns a writer prefixing lines written to stdout with [Handy CI].
+func NewWriter() io.Writer {
+	return newPrefixWriter(&sync.Mutex{}, os.Stdout, fmt.Sprint(aurora.Green("[Handy CI]"), " "))
 }
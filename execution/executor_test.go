@@ -1,9 +1,13 @@
 package execution
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -202,3 +206,268 @@ func TestNewWriter(t *testing.T) {
 	fmt.Fprintf(w, "hello")
 	fmt.Fprintf(w, "world\nagain")
 }
+
+func TestExecInRepository_FailedStepStopsRemainingSteps(t *testing.T) {
+	p := &fakeParser{executions: []Execution{
+		{Command: "false", Path: "./", Step: "allowed", AllowFailure: true},
+		{Command: "false", Path: "./", Step: "failing"},
+		{Command: "true", Path: "./", Step: "remaining"},
+	}}
+	cmd := &cobra.Command{Use: "test"}
+	ws := config.Workspace{Name: "ws"}
+	grp := config.Group{Name: "g"}
+	repo := config.Repository{Name: "r"}
+	i, err := execInRepository(cmd, nil, p, ws, grp, repo, false, false)
+	if err == nil { t.Fatalf("expected error from failing step") }
+	if i != 1 { t.Fatalf("expected failure at execution 1 got %d", i) }
+	if p.executions[0].Status != StatusFailed || p.executions[1].Status != StatusFailed || p.executions[2].Status != StatusSkipped {
+		t.Fatalf("unexpected statuses: %+v", p.executions)
+	}
+}
+
+func TestExecInRepository_ContinueRunsIndependentExecutions(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	p := &fakeParser{executions: []Execution{
+		{Command: "false", Path: first, Step: "failing"},
+		{Command: "true", Path: first, Step: "remaining"},
+		{Command: "true", Path: second, Step: "other path"},
+		{Command: "false", Path: "./"},
+		{Command: "true", Path: "./"},
+	}}
+	cmd := &cobra.Command{Use: "test"}
+	i, err := execInRepository(cmd, nil, p, config.Workspace{Name: "ws"}, config.Group{Name: "g"}, config.Repository{Name: "r"}, true, false)
+	if err == nil || i != 0 {
+		t.Fatalf("expected first failure at execution 0, got %d, %v", i, err)
+	}
+	expected := []Status{StatusFailed, StatusSkipped, StatusSucceeded, StatusFailed, StatusSucceeded}
+	for i, status := range expected {
+		if p.executions[i].Status != status {
+			t.Fatalf("expected execution %d %s, got %+v", i, status, p.executions)
+		}
+	}
+}
+
+func TestRunInRepository_WritesToStreams(t *testing.T) {
+	p := &fakeParser{executions: []Execution{{Command: "sh", Args: []string{"-c", "echo out; echo err >&2"}, Path: "./"}}}
+	cmd := &cobra.Command{Use: "test"}
+	output := &bytes.Buffer{}
+
+	_, err := runInRepository(
+		cmd, nil, p, config.Workspace{Name: "ws"}, config.Group{Name: "g"}, config.Repository{Name: "r"},
+		false, false, output, output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"PATH: r", "SCRIPT: sh", "out\n", "err\n", "<<<<<<"} {
+		if !strings.Contains(output.String(), expected) {
+			t.Fatalf("expected %q in %q", expected, output.String())
+		}
+	}
+}
+
+func TestExecInRepository_QuietSucceeded(t *testing.T) {
+	p := &fakeParser{executions: []Execution{{Command: "true", Path: "./"}}}
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().Bool(util.HandyCiFlagQuiet, true, "")
+
+	i, err := execInRepository(cmd, nil, p, config.Workspace{Name: "ws"}, config.Group{Name: "g"}, config.Repository{Name: "r"}, false, false)
+	if err != nil || i != 0 {
+		t.Fatalf("expected single status line, got %d, %v", i, err)
+	}
+	if p.executions[0].Status != StatusSucceeded {
+		t.Fatalf("unexpected status %s", p.executions[0].Status)
+	}
+}
+
+// repositoryParser returns executions running command of each repository, true when not listed.
+type repositoryParser struct {
+	commands map[string]string
+}
+
+func (r repositoryParser) CheckArgs(command *cobra.Command, args []string) error {
+	return nil
+}
+
+func (r repositoryParser) Parse(command *cobra.Command, args []string, ws config.Workspace, g config.Group, repository config.Repository) ([]Execution, error) {
+	if r.commands[repository.Name] == "invalid" {
+		return nil, ParseError{"invalid script"}
+	}
+
+	executionCommand := "true"
+	if current, listed := r.commands[repository.Name]; listed {
+		executionCommand = current
+	}
+
+	return []Execution{{Command: executionCommand, Path: "./"}}, nil
+}
+
+func newFailureModeCommand(config_ *config.Config) *cobra.Command {
+	config.HandyCiConfig = config_
+
+	cmd := &cobra.Command{Use: "test"}
+	for _, name := range []string{util.HandyCiFlagWorkspace, util.HandyCiFlagGroup, util.HandyCiFlagRepositories, util.HandyCiFlagTags, util.HandyCiFlagFrom, util.HandyCiFlagSkip} {
+		cmd.Flags().String(name, "", "")
+	}
+	for _, name := range []string{util.HandyCiFlagDryRun, util.HandyCiFlagFailAtEnd, util.HandyCiFlagFailFast, util.HandyCiFlagHelp} {
+		cmd.Flags().Bool(name, false, "")
+	}
+	cmd.Flags().BoolP(util.HandyCiFlagContinue, util.HandyCiFlagContinueShorthand, false, "")
+	return cmd
+}
+
+func TestExecute_FailAtEnd_SkipsDependents(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+
+	cmd := newFailureModeCommand(&config.Config{Workspaces: []config.Workspace{{Name: "ws", Groups: []config.Group{{Name: "g", Repositories: []config.Repository{
+		{Name: "lib"}, {Name: "app", DependsOn: []string{"lib"}}, {Name: "web", DependsOn: []string{"app"}}, {Name: "tool"},
+	}}}}}})
+	p := repositoryParser{commands: map[string]string{"lib": "false", "tool": "true"}}
+
+	err := Execute(cmd, []string{"--fail-at-end"}, p)
+	if err == nil || err.Error() != "1 repositories failed" {
+		t.Fatalf("expected failure at end, got %v", err)
+	}
+
+	state := runOf(cmd.Context())
+	failed, skipped := state.failures()
+	if len(failed) != 1 || failed[0].Repository != "lib" {
+		t.Fatalf("unexpected failed %+v", failed)
+	}
+	if len(skipped) != 2 || skipped[0].SkippedFor != "lib" || skipped[1].SkippedFor != "app" {
+		t.Fatalf("unexpected skipped %+v", skipped)
+	}
+	if state.results[len(state.results)-1].Status != StatusSucceeded {
+		t.Fatalf("expected independent repository executed, got %+v", state.results)
+	}
+}
+
+func TestExecute_FailAtEnd_OrdersByDependencies(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+
+	cmd := newFailureModeCommand(&config.Config{Workspaces: []config.Workspace{{Name: "ws", Groups: []config.Group{
+		{Name: "apps", Repositories: []config.Repository{{Name: "app", DependsOn: []string{"lib"}}}},
+		{Name: "libs", Repositories: []config.Repository{{Name: "lib"}}},
+	}}}})
+	p := repositoryParser{commands: map[string]string{"lib": "false"}}
+
+	if err := Execute(cmd, []string{"--fail-at-end"}, p); err == nil {
+		t.Fatalf("expected failure at end")
+	}
+
+	results := runOf(cmd.Context()).results
+	if len(results) != 2 || results[0].Repository != "lib" || results[0].Status != StatusFailed ||
+		results[1].Repository != "app" || results[1].SkippedFor != "lib" {
+		t.Fatalf("expected lib run before app and app skipped, got %+v", results)
+	}
+}
+
+func TestExecute_ContinueReportsParseError(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+
+	cmd := newFailureModeCommand(&config.Config{Workspaces: []config.Workspace{{Name: "ws", Groups: []config.Group{{Name: "g", Repositories: []config.Repository{
+		{Name: "a"}, {Name: "b"},
+	}}}}}})
+	p := repositoryParser{commands: map[string]string{"a": "invalid"}}
+
+	if err := Execute(cmd, []string{"-C"}, p); err != nil {
+		t.Fatalf("expected failures ignored with --continue, got %v", err)
+	}
+
+	failed, _ := runOf(cmd.Context()).failures()
+	if len(failed) != 1 || failed[0].Repository != "a" {
+		t.Fatalf("expected parse error recorded as failure, got %+v", failed)
+	}
+
+	cmd = newFailureModeCommand(config.HandyCiConfig)
+	if err := Execute(cmd, []string{"-C", "--fail-fast"}, p); err == nil {
+		t.Fatalf("expected --fail-fast to stop at the parse error")
+	}
+}
+
+func TestFailureMode_GivenOverridesDefaulted(t *testing.T) {
+	newFlags := func() *pflag.FlagSet {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.Bool(util.HandyCiFlagContinue, false, "")
+		flags.Bool(util.HandyCiFlagFailFast, false, "")
+		flags.Bool(util.HandyCiFlagFailAtEnd, false, "")
+		return flags
+	}
+
+	flags := newFlags()
+	flags.Lookup(util.HandyCiFlagFailAtEnd).Value.Set("true")
+	flags.Set(util.HandyCiFlagFailFast, "true")
+	if failFast, failAtEnd, err := failureMode(flags); err != nil || !failFast || failAtEnd {
+		t.Fatalf("expected --fail-fast given to override defaulted --fail-at-end, got %v %v %v", failFast, failAtEnd, err)
+	}
+
+	flags = newFlags()
+	flags.Lookup(util.HandyCiFlagFailFast).Value.Set("true")
+	flags.Set(util.HandyCiFlagContinue, "true")
+	if failFast, failAtEnd, err := failureMode(flags); err != nil || failFast || failAtEnd {
+		t.Fatalf("expected --continue given to override defaulted --fail-fast, got %v %v %v", failFast, failAtEnd, err)
+	}
+}
+
+func TestExecute_FailFastAndFailAtEnd(t *testing.T) {
+	cmd := newFailureModeCommand(config.HandyCiConfig)
+
+	if _, ok := Execute(cmd, []string{"--fail-fast", "--fail-at-end"}, &fakeParser{}).(ParseError); !ok {
+		t.Fatalf("expected ParseError for conflicting modes")
+	}
+}
+
+func TestTerminalStream(t *testing.T) {
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer null.Close()
+
+	if terminalStream(&bytes.Buffer{}) || terminalStream(null) {
+		t.Fatalf("expected buffers and the null device not to be terminals")
+	}
+}
+
+func TestExecute_OutputJSON(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+
+	cmd := newFailureModeCommand(&config.Config{Workspaces: []config.Workspace{{Name: "ws", Groups: []config.Group{
+		{Name: "g", Repositories: []config.Repository{{Name: "lib"}, {Name: "app"}}},
+	}}}})
+	cmd.Flags().String(util.HandyCiFlagOutput, OutputText, "")
+	cmd.Flags().Bool(util.HandyCiFlagNonInteractive, true, "")
+
+	result, err := os.CreateTemp(t.TempDir(), "result")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer result.Close()
+
+	stdout := os.Stdout
+	os.Stdout = result
+	err = Execute(cmd, []string{"--output", "json", "-C"}, repositoryParser{commands: map[string]string{"lib": "false"}})
+	os.Stdout = stdout
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, _ := os.ReadFile(result.Name())
+
+	var manifest RunManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		t.Fatalf("expected only the result on stdout, got %q: %v", content, err)
+	}
+	if len(manifest.Repositories) != 2 || manifest.Repositories[0].Status != StatusFailed ||
+		manifest.Repositories[1].Status != StatusSucceeded {
+		t.Fatalf("unexpected result %+v", manifest)
+	}
+
+	if err := Execute(cmd, []string{"--output", "yaml"}, repositoryParser{}); err == nil {
+		t.Fatalf("expected error for unknown output format")
+	}
+}
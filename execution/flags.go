@@ -0,0 +1,214 @@
+package execution
+
+import (
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// FrontOnlyAnnotation marks the flags of handy-ci which commands often have too, such as --quiet. They are
+// flags of handy-ci in front of the command only, behind it they are passed on to the command.
+const FrontOnlyAnnotation = "handy-ci-front-only"
+
+// FrontOnly marks the flags of names with FrontOnlyAnnotation.
+func FrontOnly(flags *pflag.FlagSet, names ...string) {
+	for _, name := range names {
+		flags.SetAnnotation(name, FrontOnlyAnnotation, []string{"true"})
+	}
+}
+
+// ParseFlagsAndArgs sets the flags of handy-ci found in args, in any syntax of pflag: --flag value,
+// --flag=value, -F value, -Fvalue and bundled shorthands such as -CR repository. Flags may appear
+// anywhere, except the front only ones, other args are returned in order for the command to execute, as
+// well as all args after --.
+// Unknown flags in front of the command close to a flag of handy-ci, likely mistyped, are reported instead
+// of being passed on. Behind the command they are passed on, they may be flags of the command.
+func ParseFlagsAndArgs(flags *pflag.FlagSet, args []string) ([]string, error) {
+	var cleanedArgs []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			return append(cleanedArgs, args[i+1:]...), nil
+		case strings.HasPrefix(arg, "--"):
+			consumed, err := parseLongFlag(flags, args, i, len(cleanedArgs) == 0)
+			if err != nil {
+				return cleanedArgs, err
+			}
+
+			if consumed < 0 {
+				cleanedArgs = append(cleanedArgs, arg)
+				continue
+			}
+
+			i += consumed
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			consumed, err := parseShorthandFlags(flags, args, i)
+			if err != nil {
+				return cleanedArgs, err
+			}
+
+			if consumed < 0 {
+				cleanedArgs = append(cleanedArgs, arg)
+				continue
+			}
+
+			i += consumed
+		default:
+			cleanedArgs = append(cleanedArgs, arg)
+		}
+	}
+
+	return cleanedArgs, nil
+}
+
+// parseLongFlag sets the flag of args[i], returning the number of following args consumed as value, or -1
+// when args[i] isn't a flag of handy-ci. In front of the command, unknown flags similar to one of handy-ci
+// are reported, behind it front only flags are passed on.
+func parseLongFlag(flags *pflag.FlagSet, args []string, i int, front bool) (int, error) {
+	name, value, hasValue := strings.Cut(strings.TrimPrefix(args[i], "--"), "=")
+
+	flag := flags.Lookup(name)
+	if flag != nil && !front && flag.Annotations[FrontOnlyAnnotation] != nil {
+		return -1, nil
+	}
+
+	if flag == nil {
+		if similar := similarFlag(flags, name); front && similar != "" {
+			return 0, ParseError{
+				"Unknown flag --" + name + ", did you mean --" + similar + "? Use -- in front of flags of the command.",
+			}
+		}
+
+		return -1, nil
+	}
+
+	if hasValue {
+		return 0, setFlag(flags, flag, value)
+	}
+
+	if flag.NoOptDefVal != "" {
+		return 0, setFlag(flags, flag, flag.NoOptDefVal)
+	}
+
+	value, err := parseFlagAndArg(args, i, args[i], true)
+	if err != nil {
+		return 0, err
+	}
+
+	return 1, setFlag(flags, flag, value)
+}
+
+// parseShorthandFlags sets the bundled shorthand flags of args[i], returning the number of following args
+// consumed as value, or -1 when args[i] isn't made of shorthands of handy-ci. A shorthand with value takes
+// the rest of args[i], or the next arg.
+func parseShorthandFlags(flags *pflag.FlagSet, args []string, i int) (int, error) {
+	shorthands := strings.TrimPrefix(args[i], "-")
+
+	// Check the whole bundle first, so that flags of the command are passed on untouched.
+	for j := 0; j < len(shorthands); j++ {
+		flag := flags.ShorthandLookup(shorthands[j : j+1])
+		if flag == nil {
+			return -1, nil
+		}
+
+		if flag.NoOptDefVal == "" {
+			break
+		}
+	}
+
+	for j := 0; j < len(shorthands); j++ {
+		flag := flags.ShorthandLookup(shorthands[j : j+1])
+
+		if flag.NoOptDefVal != "" {
+			err := setFlag(flags, flag, flag.NoOptDefVal)
+			if err != nil {
+				return 0, err
+			}
+
+			continue
+		}
+
+		if value := strings.TrimPrefix(shorthands[j+1:], "="); value != "" {
+			return 0, setFlag(flags, flag, value)
+		}
+
+		value, err := parseFlagAndArg(args, i, "-"+flag.Shorthand, true)
+		if err != nil {
+			return 0, err
+		}
+
+		return 1, setFlag(flags, flag, value)
+	}
+
+	return 0, nil
+}
+
+func setFlag(flags *pflag.FlagSet, flag *pflag.Flag, value string) error {
+	err := flags.Set(flag.Name, value)
+	if err != nil {
+		return ParseError{"Invalid value " + value + " for flag --" + flag.Name + ", " + err.Error()}
+	}
+
+	return nil
+}
+
+func parseFlagAndArg(args []string, i int, flag string, withValue bool) (string, error) {
+	if withValue {
+		if len(args) == i+1 {
+			return "", ParseError{
+				"Value for flag " + flag + " is required, use \"handy-ci --help\" for more information.",
+			}
+		} else {
+			return args[i+1], nil
+		}
+	}
+
+	return "true", nil
+}
+
+// similarFlag returns the flag of flags one edit away from name, for names of at least 4 characters.
+func similarFlag(flags *pflag.FlagSet, name string) string {
+	if len(name) < 4 {
+		return ""
+	}
+
+	var similar string
+
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if similar == "" && editDistance(strings.ToLower(name), flag.Name) <= 1 {
+			similar = flag.Name
+		}
+	})
+
+	return similar
+}
+
+// editDistance returns the Levenshtein distance of a and b.
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
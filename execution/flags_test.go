@@ -0,0 +1,117 @@
+package execution
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+
+	"github.com/carrchang/handy-ci/util"
+)
+
+func newFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringP(util.HandyCiFlagWorkspace, util.HandyCiFlagWorkspaceShorthand, "", "")
+	flags.StringP(util.HandyCiFlagRepositories, util.HandyCiFlagRepositoriesShorthand, "", "")
+	flags.BoolP(util.HandyCiFlagContinue, util.HandyCiFlagContinueShorthand, false, "")
+	flags.Bool(util.HandyCiFlagDryRun, false, "")
+	flags.Bool(util.HandyCiExecFlagNonStrict, false, "")
+	return flags
+}
+
+func TestParseFlagsAndArgs_Syntaxes(t *testing.T) {
+	cases := []struct {
+		args         []string
+		cleaned      []string
+		workspace    string
+		repositories string
+		toBeContinue bool
+	}{
+		{[]string{"--workspace=w1", "status"}, []string{"status"}, "w1", "", false},
+		{[]string{"-Wkeepnative", "status"}, []string{"status"}, "keepnative", "", false},
+		{[]string{"-W=w1", "status"}, []string{"status"}, "w1", "", false},
+		{[]string{"status", "-CR", "r1"}, []string{"status"}, "", "r1", true},
+		{[]string{"-CRr1,r2", "status"}, []string{"status"}, "", "r1,r2", true},
+		{[]string{"log", "-la", "--oneline", "-n", "3"}, []string{"log", "-la", "--oneline", "-n", "3"}, "", "", false},
+		{[]string{"-W", "w1", "--", "-C", "/tmp", "--workspace"}, []string{"-C", "/tmp", "--workspace"}, "w1", "", false},
+		{[]string{"--continue=false", "status"}, []string{"status"}, "", "", false},
+	}
+
+	for _, c := range cases {
+		flags := newFlagSet()
+
+		cleaned, err := ParseFlagsAndArgs(flags, c.args)
+		if err != nil {
+			t.Fatalf("unexpected error for %v: %v", c.args, err)
+		}
+
+		workspace, _ := flags.GetString(util.HandyCiFlagWorkspace)
+		repositories, _ := flags.GetString(util.HandyCiFlagRepositories)
+		toBeContinue, _ := flags.GetBool(util.HandyCiFlagContinue)
+
+		if !reflect.DeepEqual(cleaned, c.cleaned) || workspace != c.workspace ||
+			repositories != c.repositories || toBeContinue != c.toBeContinue {
+			t.Fatalf("unexpected result for %v: %#v %q %q %v", c.args, cleaned, workspace, repositories, toBeContinue)
+		}
+	}
+}
+
+func TestParseFlagsAndArgs_NonStrict(t *testing.T) {
+	flags := newFlagSet()
+
+	if _, err := ParseFlagsAndArgs(flags, []string{"ls", "--non-strict"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if nonStrict, _ := flags.GetBool(util.HandyCiExecFlagNonStrict); !nonStrict {
+		t.Fatalf("expected non-strict set")
+	}
+}
+
+func TestParseFlagsAndArgs_Errors(t *testing.T) {
+	for _, args := range [][]string{{"--dryrun"}, {"--workspaces", "w1"}, {"-CR"}, {"--workspace"}} {
+		if _, err := ParseFlagsAndArgs(newFlagSet(), args); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+}
+
+func TestParseFlagsAndArgs_SimilarFlagOfCommand(t *testing.T) {
+	flags := newFlagSet()
+	flags.Bool(util.HandyCiFlagQuiet, false, "")
+
+	cleaned, err := ParseFlagsAndArgs(flags, []string{"rebase", "--quit"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cleaned, []string{"rebase", "--quit"}) {
+		t.Fatalf("expected --quit passed on, got %#v", cleaned)
+	}
+
+	if _, err := ParseFlagsAndArgs(newFlagSet(), []string{"--dryrun", "status"}); err == nil {
+		t.Fatalf("expected error for --dryrun in front of the command")
+	}
+}
+
+func TestParseFlagsAndArgs_FrontOnly(t *testing.T) {
+	flags := newFlagSet()
+	flags.Bool(util.HandyCiFlagQuiet, false, "")
+	FrontOnly(flags, util.HandyCiFlagQuiet)
+
+	cleaned, err := ParseFlagsAndArgs(flags, []string{"pull", "--quiet", "-C"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	quiet, _ := flags.GetBool(util.HandyCiFlagQuiet)
+	toBeContinue, _ := flags.GetBool(util.HandyCiFlagContinue)
+	if !reflect.DeepEqual(cleaned, []string{"pull", "--quiet"}) || quiet || !toBeContinue {
+		t.Fatalf("expected --quiet passed on to the command, got %#v %v %v", cleaned, quiet, toBeContinue)
+	}
+
+	cleaned, err = ParseFlagsAndArgs(flags, []string{"--quiet", "pull"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if quiet, _ := flags.GetBool(util.HandyCiFlagQuiet); !reflect.DeepEqual(cleaned, []string{"pull"}) || !quiet {
+		t.Fatalf("expected --quiet of handy-ci in front of the command, got %#v %v", cleaned, quiet)
+	}
+}
@@ -1,6 +1,7 @@
 package execution
 
 import (
+  "context"
   "fmt"
   "os"
   "strings"
@@ -14,6 +15,9 @@ import (
 type GitExecution struct {
 }
 
+// gitWriteCommands are the git commands refused in read-only repositories.
+var gitWriteCommands = []string{"push", "commit", "reset", "clean"}
+
 func (s GitExecution) CheckArgs(command *cobra.Command, args []string) error {
   return nil
 }
@@ -23,8 +27,23 @@ func (s GitExecution) Parse(
   workspace config.Workspace, group config.Group, repository config.Repository) ([]Execution, error) {
   var path string
 
+  if repository.IsReadOnly() || group.IsReadOnly() || workspace.IsReadOnly() {
+    subcommand := gitSubcommand(args)
+
+    for _, writeCommand := range gitWriteCommands {
+      if subcommand == writeCommand {
+        return nil, ParseError{"Repository " + repository.Name + " is read-only, git " + subcommand + " is refused"}
+      }
+    }
+  }
+
   path = RepositoryPath(workspace, group, repository)
 
+  env, err := Environment(workspace, group, repository, "")
+  if err != nil {
+    return nil, err
+  }
+
   if util.ContainArgs(args, "clone") {
     args = append(args, RepositoryRemoteURL(repository, "origin"))
     args = append(args, repository.Name)
@@ -58,8 +77,10 @@ func (s GitExecution) Parse(
 
         executions = append(executions, Execution{
           Command: command.Use,
+          Script:  command.Use + " remote",
           Path:    path,
           Args:    executionArgs,
+          Env:     env,
         })
       } else {
         removeArgs := make([]string, len(baseArgs))
@@ -69,8 +90,10 @@ func (s GitExecution) Parse(
 
         executions = append(executions, Execution{
           Command: command.Use,
+          Script:  command.Use + " remote",
           Path:    path,
           Args:    removeArgs,
+          Env:     env,
         })
 
         addArgs := make([]string, len(baseArgs))
@@ -81,8 +104,10 @@ func (s GitExecution) Parse(
 
         executions = append(executions, Execution{
           Command: command.Use,
+          Script:  command.Use + " remote",
           Path:    path,
           Args:    addArgs,
+          Env:     env,
         })
       }
     }
@@ -93,8 +118,48 @@ func (s GitExecution) Parse(
   return []Execution{
     {
       Command: command.Use,
+      Script:  strings.TrimSpace(command.Use + " " + gitSubcommand(args)),
       Path:    path,
       Args:    args,
+      Env:     env,
     },
   }, nil
 }
+
+// gitSubcommand returns the first argument of git which isn't an option.
+func gitSubcommand(args []string) string {
+  for _, arg := range args {
+    if !strings.HasPrefix(arg, "-") {
+      return arg
+    }
+  }
+
+  return ""
+}
+
+// Clone clones repository into its repository path.
+func Clone(workspace config.Workspace, group config.Group, repository config.Repository) error {
+  if RepositoryRemoteURL(repository, "origin") == "" {
+    return ParseError{"Repository " + repository.Name + " has no origin remote to clone"}
+  }
+
+  executions, err := GitExecution{}.Parse(&cobra.Command{Use: "git"}, []string{"clone"}, workspace, group, repository)
+  if err != nil {
+    return err
+  }
+
+  ctx, stopNotify := notifyInterrupts(context.Background(), &run{})
+  defer stopNotify()
+
+  for _, execution := range executions {
+    util.Printf("SCRIPT: %s %s\n", execution.Command, strings.Join(execution.Args, " "))
+    util.Printf("PATH: %s\n", execution.Path)
+
+    err = runExecution(ctx, execution, os.Stdout, os.Stderr)
+    if err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
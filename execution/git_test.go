@@ -127,3 +127,30 @@ func TestGitExecution_Parse_RemoteCheck(t *testing.T) {
 		}
 	}
 }
+
+func TestGitExecution_Parse_ReadOnly(t *testing.T) {
+	workspace := config.Workspace{Name: "ws", Path: "/tmp/ws"}
+	readOnly, writable := true, false
+	group := config.Group{Name: "group"}
+	repo := config.Repository{Name: "repo", ReadOnly: &readOnly}
+
+	cmd := fakeCobraCommand("git")
+
+	writeArgs := [][]string{{"push"}, {"commit", "-m", "message"}, {"--no-pager", "reset", "--hard"}, {"clean", "-fd"}}
+	for _, args := range writeArgs {
+		_, err := GitExecution{}.Parse(cmd, args, workspace, group, repo)
+		if _, isParseError := err.(ParseError); !isParseError {
+			t.Fatalf("expected parse error for %v in read-only repository, got %v", args, err)
+		}
+	}
+
+	if _, err := (GitExecution{}).Parse(cmd, []string{"fetch"}, workspace, group, repo); err != nil {
+		t.Fatalf("unexpected error for fetch in read-only repository: %v", err)
+	}
+
+	group.ReadOnly = &readOnly
+	repo.ReadOnly = &writable
+	if _, err := (GitExecution{}).Parse(cmd, []string{"push"}, workspace, group, repo); err == nil {
+		t.Fatalf("expected error for push in repository of read-only group")
+	}
+}
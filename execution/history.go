@@ -0,0 +1,175 @@
+package execution
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/carrchang/handy-ci/util"
+)
+
+// DefaultKeptHistory is the number of runs kept in history when --keep-history isn't given.
+const DefaultKeptHistory = 1000
+
+// ExecutionResult is the outcome of an execution run in a repository, executions retried from the failure
+// prompt have a result per run.
+type ExecutionResult struct {
+	Script   string        `json:"script"`
+	Step     string        `json:"step,omitempty"`
+	Command  string        `json:"command"`
+	Args     []string      `json:"args,omitempty"`
+	Path     string        `json:"path"`
+	Status   Status        `json:"status"`
+	ExitCode int           `json:"exitCode"`
+	Duration time.Duration `json:"duration"`
+}
+
+// HistoryFile returns the file runs are recorded in, a JSON line per run.
+func HistoryFile() string {
+	return filepath.Join(util.Home(), "."+util.HandyCiName, "history.jsonl")
+}
+
+// ReadHistory reads the runs recorded in file, oldest first. Lines which can't be read, such as the last
+// line of a run which crashed while recording, are left out.
+func ReadHistory(file string) ([]RunManifest, error) {
+	history, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer history.Close()
+
+	var runs []RunManifest
+
+	scanner := bufio.NewScanner(history)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	for scanner.Scan() {
+		var run RunManifest
+
+		if json.Unmarshal(scanner.Bytes(), &run) == nil {
+			runs = append(runs, run)
+		}
+	}
+
+	return runs, scanner.Err()
+}
+
+// appendHistory records run in file, and removes the oldest runs so that keep runs are left. Runs finishing
+// at the same time take turns through a lock file next to file, so that none of them is lost.
+func appendHistory(file string, run RunManifest, keep int) (err error) {
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+
+	lock, err := os.OpenFile(file+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, unlockFile(lock), lock.Close())
+	}()
+
+	err = lockFile(lock)
+	if err != nil {
+		return err
+	}
+
+	history, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = history.Write(append(line, '\n'))
+
+	err = errors.Join(err, history.Close())
+	if err != nil {
+		return err
+	}
+
+	runs, err := ReadHistory(file)
+	if err != nil || len(runs) <= keep {
+		return err
+	}
+
+	var kept strings.Builder
+
+	for _, run := range runs[len(runs)-keep:] {
+		line, err := json.Marshal(run)
+		if err != nil {
+			return err
+		}
+
+		kept.Write(append(line, '\n'))
+	}
+
+	// The history is replaced through a rename so that it's never partly written for readers.
+	temporary := file + ".tmp"
+
+	err = os.WriteFile(temporary, []byte(kept.String()), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temporary, file)
+}
+
+// executionResult returns the result of execution which returned err after duration.
+func executionResult(execution Execution, err error, duration time.Duration) ExecutionResult {
+	result := ExecutionResult{
+		Script:   execution.Script,
+		Step:     execution.Step,
+		Command:  execution.Command,
+		Args:     execution.Args,
+		Path:     execution.Path,
+		Status:   resultStatus(err),
+		Duration: duration,
+	}
+
+	// Executions which didn't exit by themselves, such as the ones which couldn't start, have no exit code.
+	var exitError *exec.ExitError
+
+	switch {
+	case err == nil:
+		result.ExitCode = 0
+	case errors.As(err, &exitError) && exitError.ExitCode() >= 0:
+		result.ExitCode = exitError.ExitCode()
+	default:
+		result.ExitCode = -1
+	}
+
+	if result.Script == "" {
+		result.Script = execution.Command
+	}
+
+	return result
+}
+
+// gitHead returns the commit checked out in the git repository at path, empty when there's none.
+func gitHead(path string) string {
+	command := exec.Command("git", "rev-parse", "HEAD")
+	command.Dir = path
+
+	output, err := command.Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(output))
+}
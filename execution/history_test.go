@@ -0,0 +1,135 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/carrchang/handy-ci/config"
+)
+
+func TestAppendHistory_Retention(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+
+	for _, id := range []string{"1", "2", "3"} {
+		if err := appendHistory(file, RunManifest{ID: id, Args: []string{"exec", "mvn"}}, 2); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// A line partly written by a run which crashed is left out.
+	history, _ := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0644)
+	history.WriteString(`{"id":"4","args":`)
+	history.Close()
+
+	runs, err := ReadHistory(file)
+	if err != nil || len(runs) != 2 || runs[0].ID != "2" || runs[1].ID != "3" {
+		t.Fatalf("expected last 2 runs, got %+v, %v", runs, err)
+	}
+}
+
+func TestAppendHistory_WaitsForLock(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := appendHistory(file, RunManifest{ID: "1"}, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Another run recording its history holds the lock.
+	lock, err := os.OpenFile(file+".lock", os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer lock.Close()
+
+	if err := lockFile(lock); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	appended := make(chan error)
+	go func() { appended <- appendHistory(file, RunManifest{ID: "2"}, 2) }()
+
+	select {
+	case err := <-appended:
+		t.Fatalf("expected to wait for the lock, appended with %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := unlockFile(lock); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := <-appended; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	runs, err := ReadHistory(file)
+	if err != nil || len(runs) != 2 || runs[1].ID != "2" {
+		t.Fatalf("expected both runs, got %+v, %v", runs, err)
+	}
+}
+
+func TestReadHistory_Missing(t *testing.T) {
+	runs, err := ReadHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err != nil || runs != nil {
+		t.Fatalf("expected no runs, got %+v, %v", runs, err)
+	}
+}
+
+func TestExecutionResult(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+
+	cases := []struct {
+		err      error
+		status   Status
+		exitCode int
+	}{
+		{nil, StatusSucceeded, 0},
+		{exitErr, StatusFailed, 3},
+		{TimeoutError{"Timed out after 1s"}, StatusTimedOut, -1},
+		{errors.New("executable file not found"), StatusFailed, -1},
+	}
+
+	for _, c := range cases {
+		result := executionResult(Execution{Command: "mvn", Script: "build"}, c.err, time.Second)
+
+		if result.Status != c.status || result.ExitCode != c.exitCode || result.Script != "build" ||
+			result.Duration != time.Second {
+			t.Fatalf("unexpected result %+v for %v", result, c.err)
+		}
+	}
+
+	if result := executionResult(Execution{Command: "make"}, nil, 0); result.Script != "make" {
+		t.Fatalf("expected command as script, got %s", result.Script)
+	}
+}
+
+func TestRunInRepository_RecordsExecutions(t *testing.T) {
+	p := &fakeParser{executions: []Execution{
+		{Command: "true", Script: "build", Path: "./"},
+		{Command: "false", Script: "build", Path: "./", AllowFailure: true},
+	}}
+	state := &run{}
+	state.start(RepositoryResult{Repository: "r"})
+
+	cmd := &cobra.Command{Use: "test"}
+	cmd.SetContext(withRun(context.Background(), state))
+
+	_, err := runInRepository(
+		cmd, nil, p, config.Workspace{Name: "ws"}, config.Group{Name: "g"}, config.Repository{Name: "r"},
+		false, false, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	executions := state.results[0].Executions
+	if len(executions) != 2 || executions[0].ExitCode != 0 || executions[1].ExitCode != 1 ||
+		executions[1].Status != StatusFailed {
+		t.Fatalf("unexpected executions %+v", executions)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix && !windows
+
+package execution
+
+import (
+	"os"
+)
+
+// lockFile doesn't lock file, files can't be locked on this platform.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
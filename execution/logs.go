@@ -0,0 +1,220 @@
+package execution
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/util"
+)
+
+// DefaultKeptLogs is the number of runs whose logs are kept when --keep-logs isn't given.
+const DefaultKeptLogs = 20
+
+const manifestFile = "manifest.json"
+
+// RunManifest describes a run and the logs of its repositories, it's rewritten as the run goes on so that
+// the run can be followed from another terminal.
+type RunManifest struct {
+	ID           string             `json:"id"`
+	Args         []string           `json:"args"`
+	Started      time.Time          `json:"started"`
+	Finished     *time.Time         `json:"finished,omitempty"`
+	Repositories []RepositoryResult `json:"repositories"`
+
+	// Command is the command run in the repositories, without the options of handy-ci, such as git pull.
+	Command []string `json:"command,omitempty"`
+
+	// Selection lists the selected repositories as workspace/group/repository.
+	Selection []string `json:"selection,omitempty"`
+}
+
+// LogsDirectory returns the directory the logs of runs are written to, a directory per run.
+func LogsDirectory() string {
+	return filepath.Join(util.Home(), "."+util.HandyCiName, "logs")
+}
+
+// RunIDs returns the IDs of the runs with logs in root, oldest first.
+func RunIDs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// ReadRunManifest reads the manifest of run id in root.
+func ReadRunManifest(root string, id string) (RunManifest, error) {
+	var manifest RunManifest
+
+	content, err := os.ReadFile(filepath.Join(root, id, manifestFile))
+	if err != nil {
+		return manifest, err
+	}
+
+	err = json.Unmarshal(content, &manifest)
+
+	return manifest, err
+}
+
+// PreviousRuns returns the runs recorded in history file and in the logs in root, oldest first. Runs in
+// both are returned once.
+func PreviousRuns(history string, root string) []RunManifest {
+	runs, _ := ReadHistory(history)
+
+	recorded := map[string]bool{}
+	for _, run := range runs {
+		recorded[run.ID] = true
+	}
+
+	ids, _ := RunIDs(root)
+
+	for _, id := range ids {
+		manifest, err := ReadRunManifest(root, id)
+		if err == nil && !recorded[id] {
+			runs = append(runs, manifest)
+		}
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Started.Before(runs[j].Started) })
+
+	return runs
+}
+
+// PreviousDurations returns how long the repositories of selection took in the last of runs which ran
+// command, or in earlier ones for repositories not run then, by key of progressKey. Options of handy-ci
+// given to the runs don't matter. Repositories skipped or interrupted are left out.
+func PreviousDurations(runs []RunManifest, command []string, selection []string) map[string]time.Duration {
+	durations := map[string]time.Duration{}
+
+	for i := len(runs) - 1; i >= 0; i-- {
+		if !slices.Equal(runs[i].Command, command) {
+			continue
+		}
+
+		for _, result := range runs[i].Repositories {
+			key := progressKey(result.Workspace, result.Group, result.Repository)
+
+			if _, found := durations[key]; found || !slices.Contains(selection, key) ||
+				result.Started == nil || result.Finished == nil ||
+				result.Status != StatusSucceeded && result.Status != StatusFailed && result.Status != StatusTimedOut {
+				continue
+			}
+
+			durations[key] = result.Finished.Sub(*result.Started)
+		}
+	}
+
+	return durations
+}
+
+// runLog writes the logs and the manifest of a run.
+type runLog struct {
+	directory string
+	manifest  RunManifest
+}
+
+// runID returns the ID of the run of this process started at started, IDs sort in the order runs started.
+func runID(started time.Time) string {
+	return fmt.Sprintf("%s-%d", started.Format("20060102-150405"), os.Getpid())
+}
+
+// newRunLog creates the log directory of a new run of args in root, and removes the logs of the oldest runs
+// so that keep runs are left.
+func newRunLog(root string, args []string, keep int) (*runLog, error) {
+	started := time.Now()
+	id := runID(started)
+
+	ids, err := RunIDs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(ids) >= keep && len(ids) > 0 {
+		err = os.RemoveAll(filepath.Join(root, ids[0]))
+		if err != nil {
+			return nil, err
+		}
+
+		ids = ids[1:]
+	}
+
+	log := &runLog{
+		directory: filepath.Join(root, id),
+		manifest:  RunManifest{ID: id, Args: args, Started: started, Repositories: []RepositoryResult{}},
+	}
+
+	err = os.MkdirAll(log.directory, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return log, log.writeManifest()
+}
+
+// open opens the log of execution in repository for appending, and returns it with its path relative to
+// the run directory. Executions in other paths than the repository path, such as script paths, have logs
+// of their own.
+func (l *runLog) open(workspace config.Workspace, group config.Group, repository config.Repository,
+	execution Execution) (*os.File, string, error) {
+	name := repository.Name
+
+	relative, err := filepath.Rel(RepositoryPath(workspace, group, repository), execution.Path)
+	if err == nil && relative != "." && !strings.HasPrefix(relative, "..") {
+		name += "-" + strings.ReplaceAll(filepath.ToSlash(relative), "/", "-")
+	}
+
+	path := filepath.ToSlash(filepath.Join(workspace.Name, group.Name, name+".log"))
+
+	err = os.MkdirAll(filepath.Dir(filepath.Join(l.directory, path)), 0755)
+	if err != nil {
+		return nil, "", err
+	}
+
+	file, err := os.OpenFile(filepath.Join(l.directory, path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fmt.Fprintf(file, "$ %s %s\n", execution.Command, strings.Join(execution.Args, " "))
+
+	return file, path, nil
+}
+
+// writeManifest replaces the manifest of the run, through a rename so that readers never see it partly
+// written.
+func (l *runLog) writeManifest() error {
+	content, err := json.MarshalIndent(l.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporary := filepath.Join(l.directory, manifestFile+".tmp")
+
+	err = os.WriteFile(temporary, content, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temporary, filepath.Join(l.directory, manifestFile))
+}
@@ -0,0 +1,133 @@
+package execution
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/carrchang/handy-ci/config"
+)
+
+func TestNewRunLog_Retention(t *testing.T) {
+	root := t.TempDir()
+
+	for _, id := range []string{"20240101-000000-1", "20240102-000000-1", "20240103-000000-1"} {
+		os.MkdirAll(filepath.Join(root, id), 0755)
+	}
+
+	log, err := newRunLog(root, []string{"exec", "mvn"}, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ids, _ := RunIDs(root)
+	if len(ids) != 2 || ids[0] != "20240103-000000-1" || ids[1] != log.manifest.ID {
+		t.Fatalf("expected oldest runs removed, got %v", ids)
+	}
+
+	manifest, err := ReadRunManifest(root, log.manifest.ID)
+	if err != nil || len(manifest.Args) != 2 || manifest.Finished != nil {
+		t.Fatalf("unexpected manifest %+v, %v", manifest, err)
+	}
+}
+
+func TestRun_OpenLog(t *testing.T) {
+	root := t.TempDir()
+
+	log, err := newRunLog(root, []string{"exec", "npm"}, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	state := &run{log: log}
+	workspace := config.Workspace{Name: "ws", Path: "/root"}
+	group := config.Group{Name: "g"}
+	repository := config.Repository{Name: "r"}
+
+	state.start(RepositoryResult{Workspace: "ws", Group: "g", Repository: "r"})
+
+	for _, path := range []string{"/root/g/r", "/root/g/r", "/root/g/r/web/app"} {
+		file := state.openLog(workspace, group, repository, Execution{Command: "npm", Args: []string{"ci"}, Path: path})
+		file.Write([]byte("output\n"))
+		file.Close()
+	}
+
+	state.finish(StatusFailed)
+	state.closeLog()
+
+	manifest, _ := ReadRunManifest(root, log.manifest.ID)
+	if manifest.Finished == nil || len(manifest.Repositories) != 1 || manifest.Repositories[0].Status != StatusFailed {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+
+	logs := manifest.Repositories[0].Logs
+	if len(logs) != 2 || logs[0] != "ws/g/r.log" || logs[1] != "ws/g/r-web-app.log" {
+		t.Fatalf("unexpected logs %v", logs)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(root, log.manifest.ID, "ws/g/r.log"))
+	if string(content) != "$ npm ci\noutput\n$ npm ci\noutput\n" {
+		t.Fatalf("unexpected log %q", content)
+	}
+}
+
+func TestPreviousDurations(t *testing.T) {
+	root := t.TempDir()
+	history := filepath.Join(t.TempDir(), "history.jsonl")
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	at := func(seconds int) *time.Time {
+		at := started.Add(time.Duration(seconds) * time.Second)
+		return &at
+	}
+
+	day := func(days int) time.Time {
+		return started.AddDate(0, 0, days)
+	}
+
+	logged := []RunManifest{
+		{ID: "20240102-000000-1", Args: []string{"--progress", "exec", "mvn"}, Command: []string{"exec", "mvn"},
+			Started: day(1), Repositories: []RepositoryResult{
+				{Workspace: "ws", Group: "g", Repository: "a", Status: StatusSucceeded, Started: at(0), Finished: at(20)},
+				{Workspace: "ws", Group: "g", Repository: "b", Status: StatusInterrupted, Started: at(20), Finished: at(21)},
+			}},
+		{ID: "20240103-000000-1", Args: []string{"git", "pull"}, Command: []string{"git", "pull"},
+			Started: day(2), Repositories: []RepositoryResult{
+				{Workspace: "ws", Group: "g", Repository: "a", Status: StatusSucceeded, Started: at(0), Finished: at(1)},
+			}},
+	}
+
+	for _, manifest := range logged {
+		log := &runLog{directory: filepath.Join(root, manifest.ID), manifest: manifest}
+		os.MkdirAll(log.directory, 0755)
+		log.writeManifest()
+	}
+
+	// Older runs are only left in history, the one with logs too is read once.
+	recorded := []RunManifest{
+		{ID: "20240101-000000-1", Args: []string{"exec", "mvn", "-W", "ws"}, Command: []string{"exec", "mvn"},
+			Started: day(0), Repositories: []RepositoryResult{
+				{Workspace: "ws", Group: "g", Repository: "a", Status: StatusSucceeded, Started: at(0), Finished: at(30)},
+				{Workspace: "ws", Group: "g", Repository: "b", Status: StatusFailed, Started: at(30), Finished: at(40)},
+				{Workspace: "ws", Group: "g", Repository: "c", Status: StatusSucceeded, Started: at(40), Finished: at(50)},
+			}},
+		logged[1],
+	}
+
+	for _, manifest := range recorded {
+		if err := appendHistory(history, manifest, 10); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	runs := PreviousRuns(history, root)
+	if len(runs) != 3 || runs[0].ID != recorded[0].ID || runs[2].ID != logged[1].ID {
+		t.Fatalf("unexpected runs %v", runs)
+	}
+
+	durations := PreviousDurations(runs, []string{"exec", "mvn"}, []string{"ws/g/a", "ws/g/b"})
+	if len(durations) != 2 || durations["ws/g/a"] != 20*time.Second || durations["ws/g/b"] != 10*time.Second {
+		t.Fatalf("unexpected durations %v", durations)
+	}
+}
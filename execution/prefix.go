@@ -0,0 +1,99 @@
+package execution
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sync"
+
+	"github.com/logrusorgru/aurora"
+)
+
+// prefixColors are the colors of prefix labels, picked by the hash of the label so that a repository keeps
+// its color from run to run.
+var prefixColors = []func(interface{}) aurora.Value{
+	aurora.Cyan, aurora.Magenta, aurora.Yellow, aurora.Blue,
+	aurora.BrightCyan, aurora.BrightMagenta, aurora.BrightYellow, aurora.BrightBlue,
+}
+
+// prefixLabel returns label padded to width and colored, followed by a separator.
+func prefixLabel(label string, width int) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(label))
+
+	color := prefixColors[hash.Sum32()%uint32(len(prefixColors))]
+
+	return fmt.Sprintf("%s | ", color(fmt.Sprintf("%-*s", width, label)))
+}
+
+// prefixWriter writes the lines written to it to out, each prefixed with prefix. Partial lines are held
+// until they are completed, so that lines of writers sharing mutex don't mix. A carriage return completes a
+// line too, so that progress bars redrawing their line keep the prefix.
+type prefixWriter struct {
+	mutex  *sync.Mutex
+	out    io.Writer
+	prefix string
+	line   []byte
+}
+
+func newPrefixWriter(mutex *sync.Mutex, out io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{mutex: mutex, out: out, prefix: prefix}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.line = append(w.line, p...)
+
+	var written int
+
+	for {
+		end := bytes.IndexAny(w.line[written:], "\r\n")
+		if end < 0 {
+			break
+		}
+
+		end += written
+
+		if w.line[end] == '\r' {
+			// A carriage return at the end may be the first half of \r\n.
+			if end == len(w.line)-1 {
+				break
+			}
+
+			if w.line[end+1] == '\n' {
+				end++
+			}
+		}
+
+		_, err := fmt.Fprint(w.out, w.prefix, string(w.line[written:end+1]))
+		if err != nil {
+			return 0, err
+		}
+
+		written = end + 1
+	}
+
+	w.line = append(w.line[:0], w.line[written:]...)
+
+	return len(p), nil
+}
+
+// Flush writes the partial line held, completed with a newline.
+func (w *prefixWriter) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	line := bytes.TrimSuffix(w.line, []byte("\r"))
+	w.line = w.line[:0]
+
+	if len(line) == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintln(w.out, w.prefix+string(line))
+
+	return err
+}
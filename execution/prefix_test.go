@@ -0,0 +1,66 @@
+package execution
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPrefixWriter_PartialLines(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := newPrefixWriter(&sync.Mutex{}, out, "> ")
+
+	w.Write([]byte("hel"))
+	if out.Len() != 0 {
+		t.Fatalf("expected partial line held, got %q", out.String())
+	}
+
+	w.Write([]byte("lo\nwor"))
+	w.Write([]byte("ld\r\n"))
+	w.Write([]byte("tail"))
+	w.Flush()
+
+	if out.String() != "> hello\n> world\r\n> tail\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestPrefixWriter_CarriageReturn(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := newPrefixWriter(&sync.Mutex{}, out, "> ")
+
+	w.Write([]byte("10%\r20%\r"))
+	w.Write([]byte("100%\r"))
+	w.Flush()
+
+	if out.String() != "> 10%\r> 20%\r> 100%\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestPrefixWriter_SharedMutex(t *testing.T) {
+	out := &bytes.Buffer{}
+	mutex := &sync.Mutex{}
+	stdout := newPrefixWriter(mutex, out, "> ")
+	stderr := newPrefixWriter(mutex, out, "> ")
+
+	stdout.Write([]byte("out"))
+	stderr.Write([]byte("err\n"))
+	stdout.Write([]byte("put\n"))
+
+	if out.String() != "> err\n> output\n" {
+		t.Fatalf("expected lines not mixed, got %q", out.String())
+	}
+}
+
+func TestPrefixLabel(t *testing.T) {
+	label := prefixLabel("g/r", 6)
+
+	if !strings.Contains(label, "g/r   ") || !strings.HasSuffix(label, " | ") {
+		t.Fatalf("expected padded label, got %q", label)
+	}
+	if prefixLabel("g/r", 6) != label {
+		t.Fatalf("expected stable label")
+	}
+}
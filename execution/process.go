@@ -0,0 +1,115 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// terminateGracePeriod is the time processes are given to exit after they are signalled, before they are
+// killed.
+var terminateGracePeriod = 5 * time.Second
+
+// runExecution runs execution with the stdin of Handy CI and the given output streams, in a process group
+// of its own, in the foreground of the terminal when Handy CI is. When the run is interrupted, the process
+// group is sent the signal interrupting the run, and SIGKILL once the run is interrupted again. When the
+// deadline of the run or the timeout of execution elapses, it's sent SIGTERM, and SIGKILL if it's still
+// running after terminateGracePeriod. A process stopped by Ctrl-C in the foreground interrupts the run, as
+// Handy CI doesn't receive that Ctrl-C itself.
+func runExecution(ctx context.Context, execution Execution, stdout io.Writer, stderr io.Writer) error {
+	executionContext := ctx
+
+	if execution.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		executionContext, cancel = context.WithTimeout(ctx, execution.Timeout)
+		defer cancel()
+	}
+
+	executionCommand := exec.Command(execution.Command, execution.Args...)
+	executionCommand.Dir = execution.Path
+	executionCommand.Env = append(os.Environ(), execution.Env...)
+	executionCommand.Stdin = os.Stdin
+	executionCommand.Stdout = stdout
+	executionCommand.Stderr = stderr
+
+	foreground := configureProcessGroup(executionCommand)
+	if foreground {
+		defer restoreForeground()
+	}
+
+	err := executionCommand.Start()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- executionCommand.Wait()
+	}()
+
+	select {
+	case err = <-done:
+		if foreground && interruptedFromTerminal(err) {
+			runOf(ctx).interrupt(os.Interrupt)
+
+			return InterruptError{os.Interrupt}
+		}
+
+		return err
+	case <-executionContext.Done():
+	}
+
+	var interruptErr InterruptError
+	interrupted := errors.As(context.Cause(ctx), &interruptErr)
+
+	if interrupted {
+		signalProcessGroup(executionCommand.Process, interruptErr.Signal)
+	} else {
+		signalProcessGroup(executionCommand.Process, syscall.SIGTERM)
+	}
+
+	// An interrupted process is given the time it needs to finish, until the run is interrupted again.
+	var gracePeriod <-chan time.Time
+	if !interrupted {
+		gracePeriod = time.After(terminateGracePeriod)
+	}
+
+	select {
+	case <-done:
+	case <-gracePeriod:
+		killProcessGroup(executionCommand.Process)
+		<-done
+	case <-runOf(ctx).killed():
+		killProcessGroup(executionCommand.Process)
+		<-done
+	}
+
+	if interrupted {
+		return interruptErr
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return TimeoutError{"Deadline of run exceeded"}
+	}
+
+	if errors.Is(executionContext.Err(), context.DeadlineExceeded) {
+		return TimeoutError{"Timed out after " + execution.Timeout.String()}
+	}
+
+	return executionContext.Err()
+}
+
+// commandContext returns the context of command, which carries the deadline and the state of the run.
+func commandContext(command interface{ Context() context.Context }) context.Context {
+	if ctx := command.Context(); ctx != nil {
+		return ctx
+	}
+
+	return context.Background()
+}
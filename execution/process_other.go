@@ -0,0 +1,31 @@
+//go:build !unix
+
+package execution
+
+import (
+	"os"
+	"os/exec"
+)
+
+// configureProcessGroup leaves processes in the process group of Handy CI, process groups can't be
+// signalled on this platform.
+func configureProcessGroup(command *exec.Cmd) bool {
+	return false
+}
+
+func restoreForeground() {
+}
+
+// interruptedFromTerminal tells whether err is the exit of a process stopped by Ctrl-C, which can't be told
+// on this platform.
+func interruptedFromTerminal(err error) bool {
+	return false
+}
+
+func signalProcessGroup(process *os.Process, signal os.Signal) {
+	process.Kill()
+}
+
+func killProcessGroup(process *os.Process) {
+	process.Kill()
+}
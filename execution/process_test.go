@@ -0,0 +1,146 @@
+//go:build unix
+
+package execution
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunExecution_Timeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	start := time.Now()
+
+	err := runExecution(context.Background(), Execution{Command: "sleep", Args: []string{"10"}, Timeout: 100 * time.Millisecond}, os.Stdout, os.Stderr)
+	if _, ok := err.(TimeoutError); !ok {
+		t.Fatalf("expected TimeoutError, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected sleep to be terminated, took %v", elapsed)
+	}
+}
+
+func TestRunExecution_Deadline(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := runExecution(ctx, Execution{Command: "sleep", Args: []string{"10"}, Timeout: time.Hour}, os.Stdout, os.Stderr)
+	if err == nil || err.Error() != "Deadline of run exceeded" {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+}
+
+func TestRunExecution_WithinTimeout(t *testing.T) {
+	if _, err := exec.LookPath("true"); err != nil {
+		t.Skip("true not available")
+	}
+
+	err := runExecution(context.Background(), Execution{Command: "true", Timeout: time.Minute}, os.Stdout, os.Stderr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRunExecution_Interrupt(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	time.AfterFunc(100*time.Millisecond, func() { cancel(InterruptError{syscall.SIGINT}) })
+
+	err := runExecution(ctx, Execution{Command: "sleep", Args: []string{"10"}}, os.Stdout, os.Stderr)
+	if _, ok := err.(InterruptError); !ok {
+		t.Fatalf("expected InterruptError, got %v", err)
+	}
+}
+
+func TestInterruptedFromTerminal(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	cases := map[string]bool{
+		"kill -INT $$": true,
+		"exit 130":     true,
+		"exit 1":       false,
+		"true":         false,
+	}
+
+	for script, expected := range cases {
+		err := exec.Command("sh", "-c", script).Run()
+
+		if interrupted := interruptedFromTerminal(err); interrupted != expected {
+			t.Fatalf("expected %v for %s, got %v", expected, script, interrupted)
+		}
+	}
+}
+
+func TestRunExecution_KilledOnSecondInterrupt(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	state := &run{}
+	ctx, cancel := context.WithCancelCause(withRun(context.Background(), state))
+
+	time.AfterFunc(100*time.Millisecond, func() {
+		state.interrupt(syscall.SIGINT)
+		cancel(InterruptError{syscall.SIGINT})
+	})
+	time.AfterFunc(300*time.Millisecond, func() { state.interrupt(syscall.SIGINT) })
+
+	start := time.Now()
+
+	// The command ignores SIGINT, so only the second interrupt stops it.
+	err := runExecution(ctx, Execution{Command: "sh", Args: []string{"-c", "trap '' INT; sleep 10"}}, os.Stdout, os.Stderr)
+	if _, ok := err.(InterruptError); !ok {
+		t.Fatalf("expected InterruptError, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > terminateGracePeriod {
+		t.Fatalf("expected kill on second interrupt, took %v", elapsed)
+	}
+}
+
+func TestRunExecution_FinishesOnFirstInterrupt(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	defer func(gracePeriod time.Duration) { terminateGracePeriod = gracePeriod }(terminateGracePeriod)
+	terminateGracePeriod = 100 * time.Millisecond
+
+	state := &run{}
+	ctx, cancel := context.WithCancelCause(withRun(context.Background(), state))
+
+	time.AfterFunc(100*time.Millisecond, func() {
+		state.interrupt(syscall.SIGINT)
+		cancel(InterruptError{syscall.SIGINT})
+	})
+
+	output := &bytes.Buffer{}
+
+	// The command ignores SIGINT and finishes well after the grace period, it isn't killed.
+	err := runExecution(ctx, Execution{Command: "sh", Args: []string{"-c", "trap '' INT; sleep 1; echo finished"}},
+		output, output)
+	if _, ok := err.(InterruptError); !ok {
+		t.Fatalf("expected InterruptError, got %v", err)
+	}
+
+	if output.String() != "finished\n" {
+		t.Fatalf("expected command finished, got %q", output.String())
+	}
+}
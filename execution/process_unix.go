@@ -0,0 +1,78 @@
+//go:build unix
+
+package execution
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// configureProcessGroup makes the process of command start in a process group of its own. When Handy CI
+// runs in the foreground of a terminal, the process group is brought to the foreground, so that the
+// process can still read the terminal, and Ctrl-C reaches the process. Returns whether the process group
+// is in the foreground.
+func configureProcessGroup(command *exec.Cmd) bool {
+	attributes := &syscall.SysProcAttr{Setpgid: true}
+
+	terminal := int(os.Stdin.Fd())
+
+	if pgrp, err := foregroundProcessGroup(terminal); err == nil && pgrp == syscall.Getpgrp() {
+		attributes.Foreground = true
+		attributes.Ctty = terminal
+	}
+
+	command.SysProcAttr = attributes
+
+	return attributes.Foreground
+}
+
+// restoreForeground brings the process group of Handy CI back to the foreground of the terminal.
+func restoreForeground() {
+	// Background process groups changing the foreground process group get SIGTTOU.
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+
+	pgrp := int32(syscall.Getpgrp())
+
+	syscall.Syscall(syscall.SYS_IOCTL, os.Stdin.Fd(), uintptr(syscall.TIOCSPGRP), uintptr(unsafe.Pointer(&pgrp)))
+}
+
+func foregroundProcessGroup(terminal int) (int, error) {
+	var pgrp int32
+
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL, uintptr(terminal), uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	if errno != 0 {
+		return 0, errno
+	}
+
+	return int(pgrp), nil
+}
+
+// interruptedFromTerminal tells whether err is the exit of a process stopped by Ctrl-C, killed by SIGINT
+// or exiting with 130 like shells do.
+func interruptedFromTerminal(err error) bool {
+	var exitError *exec.ExitError
+	if !errors.As(err, &exitError) {
+		return false
+	}
+
+	status, ok := exitError.Sys().(syscall.WaitStatus)
+
+	return ok && (status.Signaled() && status.Signal() == syscall.SIGINT ||
+		status.Exited() && status.ExitStatus() == 128+int(syscall.SIGINT))
+}
+
+func signalProcessGroup(process *os.Process, signal os.Signal) {
+	if unixSignal, ok := signal.(syscall.Signal); ok {
+		syscall.Kill(-process.Pid, unixSignal)
+	}
+}
+
+func killProcessGroup(process *os.Process) {
+	syscall.Kill(-process.Pid, syscall.SIGKILL)
+}
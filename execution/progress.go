@@ -0,0 +1,362 @@
+package execution
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+)
+
+const progressRefresh = 200 * time.Millisecond
+
+// progressOutputLines is the number of last output lines of the running execution shown under it.
+const progressOutputLines = 5
+
+const progressDefaultWidth = 80
+
+var terminalEscape = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// progress draws the state of a run at the bottom of the terminal while an execution runs: repositories
+// completed out of the selected ones, failures, ETA, the running execution and its last output lines. The
+// view is erased between executions, so that what the run prints lands above it.
+type progress struct {
+	mutex sync.Mutex
+	out   io.Writer
+	width func() int
+
+	// repositories are the keys of the selected repositories, estimates their durations in previous runs.
+	repositories []string
+	estimates    map[string]time.Duration
+	finished     map[string]time.Duration
+
+	completed int
+	failed    int
+
+	repository        string
+	repositoryStarted time.Time
+	execution         string
+	executionStarted  time.Time
+	running           bool
+
+	output  []string
+	partial string
+	drawn   int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// progressKey identifies repository across runs.
+func progressKey(workspace string, group string, repository string) string {
+	return workspace + "/" + group + "/" + repository
+}
+
+// newProgress returns a progress of the run of repositories drawn to out, with the durations estimated
+// for repositories. width returns the number of columns of out, 0 when unknown.
+func newProgress(out io.Writer, width func() int, repositories []string,
+	estimates map[string]time.Duration) *progress {
+	return &progress{
+		out:          out,
+		width:        width,
+		repositories: repositories,
+		estimates:    estimates,
+		finished:     map[string]time.Duration{},
+	}
+}
+
+// start redraws the view every progressRefresh until close.
+func (p *progress) start() {
+	if p == nil {
+		return
+	}
+
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(progressRefresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.mutex.Lock()
+				p.draw(time.Now())
+				p.mutex.Unlock()
+			}
+		}
+	}()
+}
+
+// close stops redrawing and erases the view.
+func (p *progress) close() {
+	if p == nil || p.stop == nil {
+		return
+	}
+
+	close(p.stop)
+	<-p.done
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.erase()
+}
+
+// startRepository records the run starting in the repository of key.
+func (p *progress) startRepository(key string) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.repository = key
+	p.repositoryStarted = time.Now()
+}
+
+// finishRepository records the repository of key completed with status. Repositories skipped without
+// running don't have a duration.
+func (p *progress) finishRepository(key string, status Status) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.completed++
+
+	if status.IsFailure() {
+		p.failed++
+	}
+
+	if p.repository == key {
+		p.finished[key] = time.Since(p.repositoryStarted)
+		p.repository = ""
+	} else {
+		p.finished[key] = 0
+	}
+}
+
+// startExecution shows execution running until finishExecution.
+func (p *progress) startExecution(execution Execution) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.execution = strings.TrimSpace(execution.Command + " " + strings.Join(execution.Args, " "))
+	p.executionStarted = time.Now()
+	p.running = true
+	p.output = nil
+	p.partial = ""
+
+	p.draw(p.executionStarted)
+}
+
+// finishExecution erases the view until the next execution starts.
+func (p *progress) finishExecution() {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.running = false
+	p.erase()
+}
+
+// pause erases the view while print writes to the terminal, it's drawn again at the next refresh.
+func (p *progress) pause(print func()) {
+	if p == nil {
+		print()
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.erase()
+	print()
+}
+
+// Write keeps the last lines of the output of the running execution. Carriage returns start the line over,
+// like progress bars of commands do.
+func (p *progress) Write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	lines := strings.Split(p.partial+strings.ReplaceAll(string(b), "\r\n", "\n"), "\n")
+
+	for _, line := range lines[:len(lines)-1] {
+		p.output = append(p.output, lastSegment(line))
+	}
+
+	if len(p.output) > progressOutputLines {
+		p.output = p.output[len(p.output)-progressOutputLines:]
+	}
+
+	p.partial = lines[len(lines)-1]
+
+	return len(b), nil
+}
+
+func lastSegment(line string) string {
+	segments := strings.Split(line, "\r")
+
+	return segments[len(segments)-1]
+}
+
+// eta returns the time left to run the repositories not completed, at now. It's unknown when neither
+// previous runs nor completed repositories tell how long repositories take.
+func (p *progress) eta(now time.Time) (time.Duration, bool) {
+	var total time.Duration
+	var count int
+
+	for _, duration := range p.finished {
+		if duration > 0 {
+			total += duration
+			count++
+		}
+	}
+
+	var left time.Duration
+
+	for _, key := range p.repositories {
+		if _, finished := p.finished[key]; finished {
+			continue
+		}
+
+		estimate, found := p.estimates[key]
+		if !found {
+			if count == 0 {
+				return 0, false
+			}
+
+			estimate = total / time.Duration(count)
+		}
+
+		if key == p.repository {
+			estimate = max(0, estimate-now.Sub(p.repositoryStarted))
+		}
+
+		left += estimate
+	}
+
+	return left, true
+}
+
+// lines returns the lines of the view at now.
+func (p *progress) lines(now time.Time) []string {
+	status := fmt.Sprintf("%d/%d repositories", p.completed, len(p.repositories))
+
+	if p.failed > 0 {
+		status += fmt.Sprintf(", %d failed", p.failed)
+	}
+
+	if eta, known := p.eta(now); known {
+		status += ", ETA " + eta.Round(time.Second).String()
+	}
+
+	// Repositories are labelled with group and repository like status lines.
+	_, label, _ := strings.Cut(p.repository, "/")
+
+	lines := []string{
+		status,
+		fmt.Sprintf("%s (%s) %s", label, now.Sub(p.executionStarted).Round(time.Second), p.execution),
+	}
+
+	output := p.output
+	if p.partial != "" {
+		output = append(append([]string{}, output...), lastSegment(p.partial))
+	}
+
+	if len(output) > progressOutputLines {
+		output = output[len(output)-progressOutputLines:]
+	}
+
+	for _, line := range output {
+		lines = append(lines, "  | "+line)
+	}
+
+	return lines
+}
+
+// draw replaces the view drawn before with the one at now, when an execution runs.
+func (p *progress) draw(now time.Time) {
+	if !p.running {
+		p.erase()
+		return
+	}
+
+	width := progressDefaultWidth
+	if columns := p.width(); columns > 0 {
+		width = columns
+	}
+
+	lines := p.lines(now)
+
+	// The view is replaced in a single write so that it doesn't flicker.
+	var view strings.Builder
+	view.WriteString(p.eraseSequence())
+
+	for i, line := range lines {
+		columns := width - 1
+		if i == 0 {
+			columns -= len("[Handy CI] ")
+		}
+
+		// Lines are cut to the terminal so that each takes a single row to erase.
+		line = truncate(terminalEscape.ReplaceAllString(strings.ReplaceAll(line, "\t", "    "), ""), columns)
+
+		if i == 0 {
+			fmt.Fprintf(&view, "%s %s\n", aurora.Green("[Handy CI]"), line)
+		} else {
+			fmt.Fprintf(&view, "%s\n", line)
+		}
+	}
+
+	io.WriteString(p.out, view.String())
+
+	p.drawn = len(lines)
+}
+
+// truncate cuts line to columns runes.
+func truncate(line string, columns int) string {
+	runes := []rune(line)
+
+	if len(runes) > columns {
+		return string(runes[:max(0, columns)])
+	}
+
+	return line
+}
+
+// erase removes the view drawn, the cursor is left where the view started.
+func (p *progress) erase() {
+	io.WriteString(p.out, p.eraseSequence())
+}
+
+func (p *progress) eraseSequence() string {
+	if p.drawn == 0 {
+		return ""
+	}
+
+	drawn := p.drawn
+	p.drawn = 0
+
+	return fmt.Sprintf("\x1b[%dA\x1b[J", drawn)
+}
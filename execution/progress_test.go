@@ -0,0 +1,76 @@
+package execution
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress_WriteKeepsLastLines(t *testing.T) {
+	p := newProgress(&bytes.Buffer{}, func() int { return 0 }, nil, nil)
+
+	p.Write([]byte("1\n2\n3\n4\r\n5\n6\ndownloading 10%\rdownloading 50%"))
+	p.Write([]byte("\rdownloading 90%"))
+
+	expected := []string{"2", "3", "4", "5", "6"}
+	if !reflect.DeepEqual(p.output, expected) {
+		t.Fatalf("expected %q, got %q", expected, p.output)
+	}
+
+	lines := p.lines(time.Now())
+	if last := lines[len(lines)-1]; last != "  | downloading 90%" {
+		t.Fatalf("unexpected last line %q", last)
+	}
+}
+
+func TestProgress_ETA(t *testing.T) {
+	now := time.Now()
+	p := newProgress(&bytes.Buffer{}, func() int { return 0 }, []string{"ws/g/a", "ws/g/b", "ws/g/c"},
+		map[string]time.Duration{"ws/g/b": time.Minute})
+
+	if _, known := p.eta(now); known {
+		t.Fatalf("expected unknown ETA without duration of a and c")
+	}
+
+	p.finished["ws/g/a"] = 30 * time.Second
+	p.repository = "ws/g/b"
+	p.repositoryStarted = now.Add(-20 * time.Second)
+
+	// b has 40s left as in the previous run, c takes 30s like a.
+	if eta, known := p.eta(now); !known || eta != 70*time.Second {
+		t.Fatalf("unexpected ETA %s, %v", eta, known)
+	}
+}
+
+func TestProgress_DrawAndErase(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := newProgress(out, func() int { return 20 }, []string{"ws/g/a", "ws/g/b"}, nil)
+
+	p.startRepository("ws/g/a")
+	p.startExecution(Execution{Command: "mvn", Args: []string{"clean", "install"}})
+	p.Write([]byte("\x1b[1m[INFO]\x1b[0m Building a very long module name\n"))
+
+	out.Reset()
+	p.draw(time.Now())
+
+	view := out.String()
+	if !strings.HasPrefix(view, "\x1b[2A\x1b[J") {
+		t.Fatalf("expected previous view erased in %q", view)
+	}
+
+	for _, expected := range []string{"0/2 repo\n", "g/a (0s) mvn clean \n", "  | [INFO] Building\n"} {
+		if !strings.Contains(view, expected) {
+			t.Fatalf("expected %q in %q", expected, view)
+		}
+	}
+
+	out.Reset()
+	p.finishExecution()
+	p.finishRepository("ws/g/a", StatusFailed)
+
+	if out.String() != "\x1b[3A\x1b[J" || p.completed != 1 || p.failed != 1 {
+		t.Fatalf("unexpected erase %q, %d completed, %d failed", out.String(), p.completed, p.failed)
+	}
+}
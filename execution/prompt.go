@@ -0,0 +1,254 @@
+package execution
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/carrchang/handy-ci/util"
+)
+
+// failureChoice is the answer to the prompt offered when an execution fails.
+type failureChoice int
+
+const (
+	choiceRetry failureChoice = iota
+	choiceSkip
+	choiceAbort
+	choiceShell
+	choiceEdit
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+var stdinMutex sync.Mutex
+var stdinPending chan string
+
+// readStdinLine returns a channel receiving the next line of stdin, closed without a line at the end of
+// stdin. Lines are read in goroutines so that prompts can give up waiting when the run is interrupted, but
+// only when asked for, so that stdin is left to shells opened from the prompt.
+func readStdinLine() <-chan string {
+	stdinMutex.Lock()
+	defer stdinMutex.Unlock()
+
+	// A line asked for by a prompt given up is still being read.
+	if stdinPending != nil {
+		return stdinPending
+	}
+
+	line := make(chan string, 1)
+	stdinPending = line
+
+	go func() {
+		read, err := stdin.ReadString('\n')
+
+		stdinMutex.Lock()
+		defer stdinMutex.Unlock()
+
+		if err == nil {
+			line <- strings.TrimRight(read, "\r\n")
+			stdinPending = nil
+		}
+
+		close(line)
+	}()
+
+	return line
+}
+
+// promptLine writes question to stderr and returns the next line answered, false when ctx is done or
+// answers end.
+func promptLine(ctx context.Context, answers func() <-chan string, question string) (string, bool) {
+	util.Eprintf("%s", question)
+
+	select {
+	case <-ctx.Done():
+		util.Eprintln()
+		return "", false
+	case line, ok := <-answers():
+		return line, ok
+	}
+}
+
+// isInteractive tells whether failures are handled through prompts: stdin is a terminal and
+// --non-interactive isn't given.
+func isInteractive(command *cobra.Command) bool {
+	nonInteractive, _ := command.Flags().GetBool(util.HandyCiFlagNonInteractive)
+
+	return !nonInteractive && util.IsTerminal(os.Stdin)
+}
+
+// promptsFailure tells whether the failure err of an execution is handled through the failure prompt, it
+// isn't in unattended runs, or once the run is interrupted or past its deadline.
+func (r *run) promptsFailure(ctx context.Context, err error) bool {
+	if _, interrupted := err.(InterruptError); interrupted || r.unattended {
+		return false
+	}
+
+	return r.interactive && !r.interrupted() && ctx.Err() == nil
+}
+
+// resolveFailure prompts what to do about failed execution, shells are opened until another choice is
+// made and edited args are set on execution to be retried with.
+func (r *run) resolveFailure(ctx context.Context, execution *Execution, stdout io.Writer) failureChoice {
+	// Quiet output of the repository is needed to decide.
+	if output, quiet := stdout.(*bytes.Buffer); quiet {
+		output.WriteTo(os.Stdout)
+	}
+
+	for {
+		choice := promptFailure(ctx, r.answers)
+
+		switch choice {
+		case choiceShell:
+			r.shell(true)
+			err := openShell(*execution)
+			r.shell(false)
+
+			if err != nil {
+				util.Eprintf("%v\n", err)
+			}
+		case choiceEdit:
+			execution.Args = promptArgs(ctx, r.answers, *execution)
+
+			return choiceRetry
+		case choiceAbort:
+			r.abort()
+
+			return choiceAbort
+		default:
+			return choice
+		}
+	}
+}
+
+// promptFailure asks what to do about a failed execution until a valid answer is given. The run is aborted
+// when stdin ends or ctx is done.
+func promptFailure(ctx context.Context, answers func() <-chan string) failureChoice {
+	for {
+		answer, ok := promptLine(ctx, answers, "Execution failed, [r]etry, [s]kip, [a]bort, [S]hell, [e]dit args? ")
+		if !ok {
+			return choiceAbort
+		}
+
+		switch strings.TrimSpace(answer) {
+		case "r":
+			return choiceRetry
+		case "s":
+			return choiceSkip
+		case "a":
+			return choiceAbort
+		case "S":
+			return choiceShell
+		case "e":
+			return choiceEdit
+		}
+	}
+}
+
+// promptArgs asks for new args of execution, an empty answer keeps them. Args are separated by spaces,
+// and quoted with single or double quotes.
+func promptArgs(ctx context.Context, answers func() <-chan string, execution Execution) []string {
+	util.Eprintf("ARGS: %s\n", quoteArgs(execution.Args))
+
+	for {
+		answer, ok := promptLine(ctx, answers, "New args, empty to keep them: ")
+		if !ok || strings.TrimSpace(answer) == "" {
+			return execution.Args
+		}
+
+		args, err := splitArgs(answer)
+		if err == nil {
+			return args
+		}
+
+		util.Eprintf("%v\n", err)
+	}
+}
+
+// openShell runs the shell of the user in the path of execution with its environment, attached to the
+// terminal in the process group of Handy CI.
+func openShell(execution Execution) error {
+	shell := os.Getenv("SHELL")
+
+	if shell == "" && runtime.GOOS == "windows" {
+		shell = os.Getenv("COMSPEC")
+	}
+
+	if shell == "" {
+		shell = "sh"
+	}
+
+	util.Eprintf("Opening %s in %s, exit to return to the prompt\n", shell, execution.Path)
+
+	shellCommand := exec.Command(shell)
+	shellCommand.Dir = execution.Path
+	shellCommand.Env = append(os.Environ(), execution.Env...)
+	shellCommand.Stdin = os.Stdin
+	shellCommand.Stdout = os.Stdout
+	shellCommand.Stderr = os.Stderr
+
+	return shellCommand.Run()
+}
+
+// quoteArgs joins args with spaces, quoting the ones which need it for splitArgs.
+func quoteArgs(args []string) string {
+	var quoted []string
+
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+		}
+
+		quoted = append(quoted, arg)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// splitArgs splits line at spaces outside of single and double quotes, like a shell without expansions.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	var inArg bool
+
+	for _, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote in args")
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,131 @@
+package execution
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/carrchang/handy-ci/config"
+)
+
+// answers returns the lines answered to prompts, one by one.
+func answers(lines ...string) func() <-chan string {
+	channel := make(chan string, len(lines))
+
+	for _, line := range lines {
+		channel <- line
+	}
+
+	close(channel)
+
+	return func() <-chan string {
+		return channel
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	args, err := splitArgs(`clean  install -Dname="a b" 'it''s' ""`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"clean", "install", "-Dname=a b", "its", ""}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected %q, got %q", expected, args)
+	}
+
+	if _, err := splitArgs(`echo "a`); err == nil {
+		t.Fatalf("expected error for unterminated quote")
+	}
+}
+
+func TestQuoteArgs_SplitBack(t *testing.T) {
+	args := []string{"-c", "echo 'a b'", "", `say "hi"`}
+
+	split, err := splitArgs(quoteArgs(args))
+	if err != nil || !reflect.DeepEqual(split, args) {
+		t.Fatalf("expected %q, got %q, %v", args, split, err)
+	}
+}
+
+func TestPromptsFailure_NotUnattended(t *testing.T) {
+	ctx := context.Background()
+	err := errors.New("exit status 1")
+
+	if !(&run{interactive: true}).promptsFailure(ctx, err) {
+		t.Fatalf("expected failure prompted in interactive run")
+	}
+
+	if (&run{interactive: true, unattended: true}).promptsFailure(ctx, err) {
+		t.Fatalf("expected failure not prompted in unattended run")
+	}
+}
+
+func TestPromptFailure(t *testing.T) {
+	ctx := context.Background()
+
+	if choice := promptFailure(ctx, answers("x", "", " S ")); choice != choiceShell {
+		t.Fatalf("expected shell after invalid answers, got %d", choice)
+	}
+
+	if choice := promptFailure(ctx, answers("x")); choice != choiceAbort {
+		t.Fatalf("expected abort at end of answers, got %d", choice)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if choice := promptFailure(cancelled, answers()); choice != choiceAbort {
+		t.Fatalf("expected abort when interrupted, got %d", choice)
+	}
+}
+
+func TestRunInRepository_PromptEditRetriesAndSkip(t *testing.T) {
+	p := &fakeParser{executions: []Execution{
+		{Command: "sh", Args: []string{"-c", "exit 1"}, Path: "./"},
+		{Command: "sh", Args: []string{"-c", "exit 2"}, Path: "./"},
+		{Command: "true", Path: "./"},
+	}}
+	state := &run{interactive: true, answers: answers("e", "-c 'echo edited'", "s")}
+	cmd := &cobra.Command{Use: "test"}
+	cmd.SetContext(withRun(context.Background(), state))
+	output := &bytes.Buffer{}
+
+	i, err := runInRepository(
+		cmd, nil, p, config.Workspace{Name: "ws"}, config.Group{Name: "g"}, config.Repository{Name: "r"},
+		false, false, output, output)
+	if err != nil || i != 3 {
+		t.Fatalf("expected all executions run, got %d, %v", i, err)
+	}
+
+	statuses := []Status{p.executions[0].Status, p.executions[1].Status, p.executions[2].Status}
+	if !reflect.DeepEqual(statuses, []Status{StatusSucceeded, StatusSkipped, StatusSucceeded}) {
+		t.Fatalf("unexpected statuses %v", statuses)
+	}
+}
+
+func TestRunInRepository_PromptAbort(t *testing.T) {
+	p := &fakeParser{executions: []Execution{
+		{Command: "false", Path: "./"},
+		{Command: "true", Path: "./"},
+	}}
+	state := &run{interactive: true, answers: answers("a")}
+	cmd := &cobra.Command{Use: "test"}
+	cmd.SetContext(withRun(context.Background(), state))
+	output := &bytes.Buffer{}
+
+	i, err := runInRepository(
+		cmd, nil, p, config.Workspace{Name: "ws"}, config.Group{Name: "g"}, config.Repository{Name: "r"},
+		true, false, output, output)
+	if err == nil || i != 0 || !state.isAborted() {
+		t.Fatalf("expected aborted run, got %d, %v", i, err)
+	}
+
+	if p.executions[1].Status != StatusSkipped {
+		t.Fatalf("expected remaining execution skipped, got %s", p.executions[1].Status)
+	}
+}
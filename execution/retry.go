@@ -0,0 +1,90 @@
+package execution
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/carrchang/handy-ci/util"
+)
+
+// defaultBackoff is the wait before the second attempt of executions whose retry has no backoff.
+const defaultBackoff = time.Second
+
+// Retry tells how many times a failed execution is run, and which failures are retried.
+type Retry struct {
+	Attempts      int
+	Backoff       time.Duration
+	OnExitCodes   []int
+	OnOutputMatch *regexp.Regexp
+}
+
+// retryable tells whether err, with output of the execution, is a failure retry applies to. Failures to start
+// the command are not retried.
+func (r Retry) retryable(err error, output []byte) bool {
+	var exitError *exec.ExitError
+
+	_, timedOut := err.(TimeoutError)
+	exited := errors.As(err, &exitError)
+
+	switch {
+	case !exited && !timedOut:
+		return false
+	case len(r.OnExitCodes) > 0 && (!exited || !slices.Contains(r.OnExitCodes, exitError.ExitCode())):
+		return false
+	}
+
+	return r.OnOutputMatch == nil || r.OnOutputMatch.Match(output)
+}
+
+// runExecutionWithRetry runs execution like runExecution until it succeeds or its retry attempts are used up, waiting the
+// backoff of its retry before the second attempt and twice as long before each further one. Executions
+// stopped by the deadline of the run are not retried.
+func runExecutionWithRetry(ctx context.Context, execution Execution, stdout io.Writer, stderr io.Writer) error {
+	backoff := execution.Retry.Backoff
+	if backoff == 0 {
+		backoff = defaultBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		var output *bytes.Buffer
+
+		attemptStdout, attemptStderr := stdout, stderr
+
+		if execution.Retry.OnOutputMatch != nil {
+			output = &bytes.Buffer{}
+			attemptStdout, attemptStderr = io.MultiWriter(stdout, output), io.MultiWriter(stderr, output)
+		}
+
+		err := runExecution(ctx, execution, attemptStdout, attemptStderr)
+		if err == nil || attempt >= execution.Retry.Attempts || ctx.Err() != nil {
+			return err
+		}
+
+		var captured []byte
+		if output != nil {
+			captured = output.Bytes()
+		}
+
+		if !execution.Retry.retryable(err, captured) {
+			return err
+		}
+
+		util.Fprintf(stdout, "ATTEMPT %d/%d FAILED: %v, retry in %s\n", attempt, execution.Retry.Attempts, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+
+		util.Fprintf(stdout, "ATTEMPT %d/%d: %s\n", attempt+1, execution.Retry.Attempts, execution.Command)
+	}
+}
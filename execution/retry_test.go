@@ -0,0 +1,82 @@
+//go:build unix
+
+package execution
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingExecution runs script with sh, after appending a line to the returned file on each attempt.
+func countingExecution(t *testing.T, script string, retry Retry) (Execution, string) {
+	attempts := filepath.Join(t.TempDir(), "attempts")
+
+	return Execution{Command: "sh", Args: []string{"-c", "echo >> " + attempts + "; " + script}, Retry: retry}, attempts
+}
+
+func countAttempts(t *testing.T, attempts string) int {
+	content, err := os.ReadFile(attempts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return strings.Count(string(content), "\n")
+}
+
+func TestRunExecutionWithRetry_FailsAfterAttempts(t *testing.T) {
+	execution, attempts := countingExecution(t, "exit 3", Retry{Attempts: 3, Backoff: time.Millisecond})
+
+	if err := runExecutionWithRetry(context.Background(), execution, os.Stdout, os.Stderr); err == nil {
+		t.Fatalf("expected error after final attempt")
+	}
+	if count := countAttempts(t, attempts); count != 3 {
+		t.Fatalf("expected 3 attempts, got %d", count)
+	}
+}
+
+func TestRunExecutionWithRetry_SucceedsOnRetry(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	execution, attempts := countingExecution(
+		t, "test -f "+marker+" || { touch "+marker+"; exit 1; }", Retry{Attempts: 3, Backoff: time.Millisecond})
+
+	if err := runExecutionWithRetry(context.Background(), execution, os.Stdout, os.Stderr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count := countAttempts(t, attempts); count != 2 {
+		t.Fatalf("expected 2 attempts, got %d", count)
+	}
+}
+
+func TestRunExecutionWithRetry_OnExitCodes(t *testing.T) {
+	execution, attempts := countingExecution(
+		t, "exit 3", Retry{Attempts: 3, Backoff: time.Millisecond, OnExitCodes: []int{128}})
+
+	runExecutionWithRetry(context.Background(), execution, os.Stdout, os.Stderr)
+
+	if count := countAttempts(t, attempts); count != 1 {
+		t.Fatalf("expected exit code 3 not retried, got %d attempts", count)
+	}
+}
+
+func TestRunExecutionWithRetry_OnOutputMatch(t *testing.T) {
+	retry := Retry{Attempts: 2, Backoff: time.Millisecond, OnOutputMatch: regexp.MustCompile("ETIMEDOUT")}
+
+	execution, attempts := countingExecution(t, "echo npm ERR! ETIMEDOUT; exit 1", retry)
+	runExecutionWithRetry(context.Background(), execution, os.Stdout, os.Stderr)
+
+	if count := countAttempts(t, attempts); count != 2 {
+		t.Fatalf("expected matching output retried, got %d attempts", count)
+	}
+
+	execution, attempts = countingExecution(t, "echo npm ERR! E404; exit 1", retry)
+	runExecutionWithRetry(context.Background(), execution, os.Stdout, os.Stderr)
+
+	if count := countAttempts(t, attempts); count != 1 {
+		t.Fatalf("expected other output not retried, got %d attempts", count)
+	}
+}
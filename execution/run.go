@@ -0,0 +1,522 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/util"
+)
+
+const StatusInterrupted Status = "interrupted"
+const StatusRunning Status = "running"
+
+// IsFailure tells whether the status is of an execution or repository which didn't succeed.
+func (s Status) IsFailure() bool {
+	return s == StatusFailed || s == StatusTimedOut || s == StatusInterrupted
+}
+
+// InterruptError is returned by executions stopped because Handy CI received Signal.
+type InterruptError struct {
+	Signal os.Signal
+}
+
+// ExitCode returns the exit code of Handy CI stopped by the signal, 128 plus the signal number.
+func (e InterruptError) ExitCode() int {
+	if signal, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(signal)
+	}
+
+	return 1
+}
+
+func (e InterruptError) Error() string {
+	if e.Signal == os.Interrupt {
+		return "Interrupted by Ctrl-C"
+	}
+
+	return "Interrupted by signal " + e.Signal.String()
+}
+
+// RepositoryResult is the outcome of a command in a repository.
+type RepositoryResult struct {
+	Workspace  string   `json:"workspace"`
+	Group      string   `json:"group"`
+	Repository string   `json:"repository"`
+	Status     Status   `json:"status"`
+	Logs       []string `json:"logs,omitempty"`
+
+	// SkippedFor is the failed repository the repository depends on, when it's skipped with --fail-at-end.
+	SkippedFor string `json:"skippedFor,omitempty"`
+
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	// Head is the commit checked out in the repository when the command started, when the run is recorded
+	// in history.
+	Head       string            `json:"head,omitempty"`
+	Executions []ExecutionResult `json:"executions,omitempty"`
+}
+
+// run is the state of a run of exec or git command shared by its executions, carried by the context of
+// the command.
+type run struct {
+	mutex      sync.Mutex
+	interrupts int
+	signal     os.Signal
+	kill       chan struct{}
+	results    []RepositoryResult
+	resumeFrom string
+
+	// labelWidth is the width prefix labels of the selected repositories are aligned to.
+	labelWidth int
+
+	// failAtEnd skips repositories depending on failed ones, instead of stopping at the first failure.
+	failAtEnd bool
+
+	// showIf matches the quiet output of repositories shown although they succeeded.
+	showIf *regexp.Regexp
+
+	// log writes the logs of executions and the manifest of the run, nil when logs are disabled.
+	log *runLog
+
+	// interactive prompts what to do when an execution fails, reading answers.
+	interactive bool
+	answers     func() <-chan string
+
+	// unattended handles failures as --continue, --fail-at-end or --fail-fast say, without prompting.
+	unattended bool
+
+	// aborted stops the run after a failure, also with --continue.
+	aborted bool
+
+	// inShell ignores Ctrl-C while a shell opened from the failure prompt runs, it's the shell's.
+	inShell bool
+
+	// progress shows the progress of the run in the terminal, nil when it isn't shown.
+	progress *progress
+
+	// history is the file the run is recorded in, keeping keepHistory runs, empty when it isn't recorded.
+	history     string
+	keepHistory int
+}
+
+type runKey struct{}
+
+// withRun returns ctx carrying state.
+func withRun(ctx context.Context, state *run) context.Context {
+	return context.WithValue(ctx, runKey{}, state)
+}
+
+// runOf returns the state of the run ctx belongs to, or a state of its own when ctx carries none.
+func runOf(ctx context.Context) *run {
+	if state, ok := ctx.Value(runKey{}).(*run); ok {
+		return state
+	}
+
+	return &run{}
+}
+
+func (r *run) interrupt(signal os.Signal) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.interrupts++
+
+	if r.signal == nil || signal != os.Interrupt {
+		r.signal = signal
+	}
+
+	if r.interrupts == 2 {
+		close(r.killChannel())
+	}
+
+	return r.interrupts
+}
+
+// killed returns a channel closed when the run is interrupted a second time, running processes are killed
+// then.
+func (r *run) killed() <-chan struct{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.killChannel()
+}
+
+func (r *run) killChannel() chan struct{} {
+	if r.kill == nil {
+		r.kill = make(chan struct{})
+	}
+
+	return r.kill
+}
+
+// interrupted tells whether Handy CI received Ctrl-C or SIGTERM, no more repositories are scheduled then.
+func (r *run) interrupted() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.interrupts > 0
+}
+
+// abort stops the run, no more repositories are scheduled.
+func (r *run) abort() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.aborted = true
+}
+
+// isAborted tells whether the run was aborted from the failure prompt.
+func (r *run) isAborted() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.aborted
+}
+
+// shell records whether a shell opened from the failure prompt runs.
+func (r *run) shell(open bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.inShell = open
+}
+
+// ignoresInterrupts tells whether signals go to a shell opened from the failure prompt rather than the run.
+func (r *run) ignoresInterrupts() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.inShell
+}
+
+// start records the command starting in the repository of result.
+func (r *run) start(result RepositoryResult) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	started := time.Now()
+	result.Status = StatusRunning
+	result.Started = &started
+	r.results = append(r.results, result)
+
+	r.progress.startRepository(progressKey(result.Workspace, result.Group, result.Repository))
+
+	r.writeManifest()
+}
+
+// finish records the status of the repository last started.
+func (r *run) finish(status Status) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	finished := time.Now()
+	current := &r.results[len(r.results)-1]
+	current.Status = status
+	current.Finished = &finished
+
+	r.progress.finishRepository(progressKey(current.Workspace, current.Group, current.Repository), status)
+
+	if status == StatusInterrupted && r.resumeFrom == "" {
+		r.resumeFrom = current.Repository
+	}
+
+	r.writeManifest()
+}
+
+// recordExecution adds result to the executions of the repository last started.
+func (r *run) recordExecution(result ExecutionResult) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.results) == 0 {
+		return
+	}
+
+	current := &r.results[len(r.results)-1]
+	current.Executions = append(current.Executions, result)
+
+	r.writeManifest()
+}
+
+// recordHistory records the run of args running command on the selected repositories in history, when it's
+// enabled.
+func (r *run) recordHistory(args []string, command []string, selection []string, started time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.history == "" {
+		return
+	}
+
+	if err := appendHistory(r.history, r.manifest(args, command, selection, started), r.keepHistory); err != nil {
+		util.Eprintf("Unable to record run in history, %v\n", err)
+	}
+}
+
+// writeResult writes the run of args running command on the selected repositories to out as JSON.
+func (r *run) writeResult(out io.Writer, args []string, command []string, selection []string, started time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(r.manifest(args, command, selection, started)); err != nil {
+		util.Eprintf("Unable to write result of run, %v\n", err)
+	}
+}
+
+// manifest returns the manifest of the run of args running command on the selected repositories, finished
+// now. The mutex must be held.
+func (r *run) manifest(args []string, command []string, selection []string, started time.Time) RunManifest {
+	finished := time.Now()
+	manifest := RunManifest{
+		ID:           runID(started),
+		Args:         args,
+		Command:      command,
+		Started:      started,
+		Finished:     &finished,
+		Selection:    selection,
+		Repositories: r.results,
+	}
+
+	if manifest.Repositories == nil {
+		manifest.Repositories = []RepositoryResult{}
+	}
+
+	if r.log != nil {
+		manifest.ID = r.log.manifest.ID
+	}
+
+	return manifest
+}
+
+// openLog opens the log of execution in the repository last started, nil when logs are disabled.
+func (r *run) openLog(workspace config.Workspace, group config.Group, repository config.Repository,
+	execution Execution) io.WriteCloser {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.log == nil || len(r.results) == 0 {
+		return nil
+	}
+
+	file, path, err := r.log.open(workspace, group, repository, execution)
+	if err != nil {
+		util.Eprintf("Unable to write log, %v\n", err)
+		return nil
+	}
+
+	current := &r.results[len(r.results)-1]
+	if !util.ContainArgs(current.Logs, path) {
+		current.Logs = append(current.Logs, path)
+		r.writeManifest()
+	}
+
+	return file
+}
+
+// closeLog records the end of the run in its manifest.
+func (r *run) closeLog() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.log == nil {
+		return
+	}
+
+	finished := time.Now()
+	r.log.manifest.Finished = &finished
+
+	r.writeManifest()
+}
+
+func (r *run) writeManifest() {
+	if r.log == nil {
+		return
+	}
+
+	r.log.manifest.Repositories = r.results
+
+	if err := r.log.writeManifest(); err != nil {
+		util.Eprintf("Unable to write log manifest, %v\n", err)
+	}
+}
+
+// skipForFailure records the repository of result skipped because it depends on the failed dependency.
+func (r *run) skipForFailure(result RepositoryResult, dependency string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	result.Status = StatusSkipped
+	result.SkippedFor = dependency
+	r.results = append(r.results, result)
+
+	r.progress.finishRepository(progressKey(result.Workspace, result.Group, result.Repository), StatusSkipped)
+
+	r.writeManifest()
+}
+
+// failedDependency returns the first repository repository depends on which failed or was skipped for a
+// failure, or an empty string.
+func (r *run) failedDependency(workspace config.Workspace, repository config.Repository) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, dependency := range repository.DependsOn {
+		for _, result := range r.results {
+			if result.Workspace == workspace.Name && result.Repository == dependency &&
+				(result.Status == StatusFailed || result.Status == StatusTimedOut || result.SkippedFor != "") {
+				return dependency
+			}
+		}
+	}
+
+	return ""
+}
+
+// failures returns the repositories which failed or timed out, and the repositories skipped because of
+// them.
+func (r *run) failures() ([]RepositoryResult, []RepositoryResult) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var failed, skipped []RepositoryResult
+
+	for _, result := range r.results {
+		switch {
+		case result.Status == StatusFailed || result.Status == StatusTimedOut:
+			failed = append(failed, result)
+		case result.SkippedFor != "":
+			skipped = append(skipped, result)
+		}
+	}
+
+	return failed, skipped
+}
+
+// stopBefore records repository as the first repository not executed.
+func (r *run) stopBefore(repository string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.resumeFrom == "" {
+		r.resumeFrom = repository
+	}
+}
+
+// resultStatus returns the status of a repository the command returned err in.
+func resultStatus(err error) Status {
+	switch err.(type) {
+	case nil:
+		return StatusSucceeded
+	case InterruptError:
+		return StatusInterrupted
+	case TimeoutError:
+		return StatusTimedOut
+	}
+
+	return StatusFailed
+}
+
+// notifyInterrupts makes SIGINT and SIGTERM received by Handy CI stop the run of state. The first one stops
+// scheduling repositories and cancels the returned context with an InterruptError, so that the signal is
+// forwarded to the running process group, which is waited for, the second one kills it. Ctrl-C in a terminal reaches commands
+// in the foreground rather than Handy CI, runExecution stops the run when they are stopped by it. The
+// returned function stops watching signals.
+func notifyInterrupts(ctx context.Context, state *run) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case received := <-signals:
+				if received == os.Interrupt && state.ignoresInterrupts() {
+					continue
+				}
+
+				if state.interrupt(received) > 1 {
+					continue
+				}
+
+				state.progress.pause(func() {
+					util.Eprintln()
+					util.Eprintln("Interrupted, waiting for the current command to finish, press Ctrl-C again to kill it")
+				})
+
+				cancel(InterruptError{received})
+			}
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel(nil)
+	}
+}
+
+// printInterruptSummary prints what was completed and interrupted by Ctrl-C or SIGTERM, and where to resume.
+func printInterruptSummary(state *run) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	var completed, interrupted []string
+
+	for _, result := range state.results {
+		switch result.Status {
+		case StatusInterrupted:
+			interrupted = append(interrupted, result.Repository)
+		default:
+			completed = append(completed, result.Repository+" ("+string(result.Status)+")")
+		}
+	}
+
+	util.Printf("INTERRUPTED\n")
+
+	if len(completed) > 0 {
+		util.Printf("COMPLETED: %s\n", strings.Join(completed, ", "))
+	}
+
+	if len(interrupted) > 0 {
+		util.Printf("INTERRUPTED: %s\n", strings.Join(interrupted, ", "))
+	}
+
+	if state.resumeFrom != "" {
+		util.Printf("RESUME: --%s %s\n", util.HandyCiFlagFrom, state.resumeFrom)
+	}
+}
+
+// printFailureSummary prints the repositories which failed, and the ones skipped because of them.
+func printFailureSummary(failed []RepositoryResult, skipped []RepositoryResult) {
+	util.Printf("FAILED:\n")
+
+	for _, result := range failed {
+		util.Printf("  %s/%s (%s)\n", result.Group, result.Repository, result.Status)
+	}
+
+	if len(skipped) > 0 {
+		util.Printf("SKIPPED BECAUSE OF FAILURE:\n")
+
+		for _, result := range skipped {
+			util.Printf("  %s/%s, depends on %s\n", result.Group, result.Repository, result.SkippedFor)
+		}
+	}
+}
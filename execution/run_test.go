@@ -0,0 +1,68 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestResultStatus(t *testing.T) {
+	cases := map[Status]error{
+		StatusSucceeded:   nil,
+		StatusInterrupted: InterruptError{os.Interrupt},
+		StatusTimedOut:    TimeoutError{"Timed out after 1s"},
+		StatusFailed:      errors.New("exit status 1"),
+	}
+
+	for expected, err := range cases {
+		if status := resultStatus(err); status != expected {
+			t.Fatalf("expected %s for %v, got %s", expected, err, status)
+		}
+	}
+}
+
+func TestRun_ResumeFrom(t *testing.T) {
+	state := &run{}
+
+	state.start(RepositoryResult{Repository: "a"})
+	state.finish(StatusSucceeded)
+	state.stopBefore("b")
+	state.stopBefore("c")
+
+	if state.resumeFrom != "b" {
+		t.Fatalf("expected resume from first repository not executed, got %s", state.resumeFrom)
+	}
+
+	state = &run{}
+
+	state.start(RepositoryResult{Repository: "a"})
+	state.finish(StatusInterrupted)
+	state.stopBefore("b")
+
+	if state.resumeFrom != "a" {
+		t.Fatalf("expected resume from interrupted repository, got %s", state.resumeFrom)
+	}
+}
+
+func TestRunOf(t *testing.T) {
+	state := &run{}
+
+	if runOf(withRun(context.Background(), state)) != state {
+		t.Fatalf("expected state carried by context")
+	}
+
+	if runOf(context.Background()) == nil {
+		t.Fatalf("expected state of its own for context without run")
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	if code := (InterruptError{syscall.SIGINT}).ExitCode(); code != 130 {
+		t.Fatalf("expected 130 for Ctrl-C, got %d", code)
+	}
+	if code := (TimeoutError{"Deadline of run exceeded"}).ExitCode(); code != 124 {
+		t.Fatalf("expected 124 for timeout, got %d", code)
+	}
+}
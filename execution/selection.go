@@ -0,0 +1,209 @@
+package execution
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/util"
+)
+
+// Target is a repository selected for execution, with the workspace and group it belongs to.
+type Target struct {
+	Workspace  config.Workspace
+	Group      config.Group
+	Repository config.Repository
+}
+
+// Selection returns the repositories selected by the workspace, group, repositories, tags, from, skip and
+// include-archived flags of command, in the order they are executed.
+func Selection(command *cobra.Command) []Target {
+	var targets []Target
+
+	for _, workspace := range selectedWorkspaces(command) {
+		for _, group := range selectedGroups(command, workspace) {
+			targets = append(targets, groupTargets(command, workspace, group)...)
+		}
+	}
+
+	return targets
+}
+
+// groupTargets returns the selected repositories of group in workspace.
+func groupTargets(command *cobra.Command, workspace config.Workspace, group config.Group) []Target {
+	var targets []Target
+
+	for _, repository := range selectedRepositories(command, workspace, group) {
+		targets = append(targets, Target{Workspace: workspace, Group: group, Repository: repository})
+	}
+
+	return targets
+}
+
+func selectedWorkspaces(command *cobra.Command) []config.Workspace {
+	currentWorkspace, _ := command.Flags().GetString(util.HandyCiFlagWorkspace)
+
+	var workspaces []config.Workspace
+
+	for _, workspace := range Workspaces() {
+		if currentWorkspace == "" || workspace.Name == currentWorkspace {
+			workspaces = append(workspaces, workspace)
+		}
+	}
+
+	return workspaces
+}
+
+func selectedGroups(command *cobra.Command, workspace config.Workspace) []config.Group {
+	currentGroup, _ := command.Flags().GetString(util.HandyCiFlagGroup)
+
+	var groups []config.Group
+
+	for _, group := range workspace.Groups {
+		// A group path selects the group and its sub-groups.
+		if currentGroup == "" || group.Name == currentGroup || strings.HasPrefix(group.Name, currentGroup+"/") {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
+}
+
+// selectedRepositories returns the repositories of group in workspace selected by the flags of command. The
+// from flag applies across the selection, groups following the one of the repository are selected whole.
+func selectedRepositories(command *cobra.Command, workspace config.Workspace, group config.Group) []config.Repository {
+	targetRepositories := flagValues(command, util.HandyCiFlagRepositories)
+	tagsAsArgument := flagValues(command, util.HandyCiFlagTags)
+	skippedRepositories := flagValues(command, util.HandyCiFlagSkip)
+	fromRepository, _ := command.Flags().GetString(util.HandyCiFlagFrom)
+	includeArchived, _ := command.Flags().GetBool(util.HandyCiFlagIncludeArchived)
+
+	var repositories []config.Repository
+	resume := fromRepository != "" && reachedBefore(command, workspace, group, fromRepository)
+
+	for _, repository := range group.Repositories {
+		if !resume && fromRepository != "" {
+			if strings.EqualFold(repository.Name, fromRepository) {
+				resume = true
+			} else {
+				continue
+			}
+		}
+
+		if util.ContainArgs(skippedRepositories, repository.Name) {
+			continue
+		}
+
+		if !includeArchived && (!repository.IsEnabled() || repository.IsArchived()) {
+			continue
+		}
+
+		if !repositoryTagsContainAllTagsAsArgument(repository, tagsAsArgument) {
+			continue
+		}
+
+		if len(targetRepositories) > 0 && !util.ContainArgs(targetRepositories, repository.Name) {
+			continue
+		}
+
+		repositories = append(repositories, repository)
+	}
+
+	return repositories
+}
+
+// reachedBefore tells whether repository is in a group selected before group in workspace.
+func reachedBefore(command *cobra.Command, workspace config.Workspace, group config.Group, repository string) bool {
+	for _, selectedWorkspace := range selectedWorkspaces(command) {
+		for _, selectedGroup := range selectedGroups(command, selectedWorkspace) {
+			if selectedWorkspace.Name == workspace.Name && selectedGroup.Name == group.Name {
+				return false
+			}
+
+			for _, candidate := range selectedGroup.Repositories {
+				if strings.EqualFold(candidate.Name, repository) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// flagValues returns the trimmed, non-empty values of a comma-delimited flag.
+func flagValues(command *cobra.Command, name string) []string {
+	var values []string
+
+	valuesInString, _ := command.Flags().GetString(name)
+	if valuesInString != "" {
+		for _, value := range strings.Split(valuesInString, ",") {
+			trimmed := strings.Trim(value, " ")
+			if trimmed != "" {
+				values = append(values, trimmed)
+			}
+		}
+	}
+
+	return values
+}
+
+func repositoryTagsContainAllTagsAsArgument(repository config.Repository, tagsAsArgument []string) bool {
+	for _, tagAsArgument := range tagsAsArgument {
+		if tagAsArgument != "" && !util.ContainArgs(repository.Tags, tagAsArgument) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// OrderByDependencies orders targets so that each repository follows the repositories it depends on,
+// keeping the selection order otherwise. Dependencies outside of targets are ignored.
+func OrderByDependencies(targets []Target) ([]Target, error) {
+	var ordered []Target
+
+	visited := map[int]bool{}
+	visiting := map[int]bool{}
+
+	var visit func(i int, chain []string) error
+	visit = func(i int, chain []string) error {
+		if visited[i] {
+			return nil
+		}
+
+		chain = append(chain, targets[i].Repository.Name)
+
+		if visiting[i] {
+			return ParseError{"Repository dependency cycle " + strings.Join(chain, " -> ")}
+		}
+
+		visiting[i] = true
+
+		for _, dependency := range targets[i].Repository.DependsOn {
+			for j, target := range targets {
+				if target.Workspace.Name == targets[i].Workspace.Name && target.Repository.Name == dependency {
+					err := visit(j, chain)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		visited[i] = true
+		ordered = append(ordered, targets[i])
+
+		return nil
+	}
+
+	for i := range targets {
+		err := visit(i, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
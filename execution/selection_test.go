@@ -0,0 +1,139 @@
+package execution
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/carrchang/handy-ci/config"
+	"github.com/carrchang/handy-ci/util"
+)
+
+func newSelectionCommand() *cobra.Command {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String(util.HandyCiFlagWorkspace, "", "")
+	cmd.Flags().String(util.HandyCiFlagGroup, "", "")
+	cmd.Flags().String(util.HandyCiFlagRepositories, "", "")
+	cmd.Flags().String(util.HandyCiFlagTags, "", "")
+	cmd.Flags().String(util.HandyCiFlagFrom, "", "")
+	cmd.Flags().String(util.HandyCiFlagSkip, "", "")
+	cmd.Flags().Bool(util.HandyCiFlagIncludeArchived, false, "")
+	return cmd
+}
+
+func TestSelection(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+	config.HandyCiConfig = &config.Config{Workspaces: []config.Workspace{
+		{Name: "w1", Groups: []config.Group{{Name: "g1", Repositories: []config.Repository{
+			{Name: "a", Tags: []string{"t"}}, {Name: "b"}, {Name: "c", Tags: []string{"t"}},
+		}}}},
+		{Name: "w2", Groups: []config.Group{{Name: "g2", Repositories: []config.Repository{{Name: "d", Tags: []string{"t"}}}}}},
+	}}
+
+	names := func(targets []Target) string {
+		var result string
+		for _, target := range targets {
+			result += target.Workspace.Name + "/" + target.Group.Name + "/" + target.Repository.Name + " "
+		}
+		return result
+	}
+
+	cmd := newSelectionCommand()
+	if got := names(Selection(cmd)); got != "w1/g1/a w1/g1/b w1/g1/c w2/g2/d " {
+		t.Fatalf("unexpected selection: %s", got)
+	}
+
+	cmd.Flags().Set(util.HandyCiFlagTags, "t")
+	cmd.Flags().Set(util.HandyCiFlagSkip, "d")
+	if got := names(Selection(cmd)); got != "w1/g1/a w1/g1/c " {
+		t.Fatalf("unexpected selection by tags and skip: %s", got)
+	}
+
+	cmd = newSelectionCommand()
+	cmd.Flags().Set(util.HandyCiFlagWorkspace, "w1")
+	cmd.Flags().Set(util.HandyCiFlagFrom, "b")
+	if got := names(Selection(cmd)); got != "w1/g1/b w1/g1/c " {
+		t.Fatalf("unexpected selection from b: %s", got)
+	}
+
+	cmd = newSelectionCommand()
+	cmd.Flags().Set(util.HandyCiFlagFrom, "c")
+	if got := names(Selection(cmd)); got != "w1/g1/c w2/g2/d " {
+		t.Fatalf("unexpected selection from c across workspaces: %s", got)
+	}
+}
+
+func TestSelection_ExcludesDisabledAndArchived(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+	disabled, archived := false, true
+	config.HandyCiConfig = &config.Config{Workspaces: []config.Workspace{
+		{Name: "w", Groups: []config.Group{{Name: "g", Repositories: []config.Repository{
+			{Name: "a"}, {Name: "b", Enabled: &disabled}, {Name: "c", Archived: &archived},
+		}}}},
+	}}
+
+	count := func(cmd *cobra.Command) int { return len(Selection(cmd)) }
+
+	cmd := newSelectionCommand()
+	if got := count(cmd); got != 1 {
+		t.Fatalf("expected only enabled, not archived repository selected, got %d", got)
+	}
+
+	cmd.Flags().Set(util.HandyCiFlagIncludeArchived, "true")
+	if got := count(cmd); got != 3 {
+		t.Fatalf("expected all repositories selected with include-archived, got %d", got)
+	}
+}
+
+func TestSelection_GroupPathSelectsSubGroups(t *testing.T) {
+	old := config.HandyCiConfig
+	defer func() { config.HandyCiConfig = old }()
+	config.HandyCiConfig = &config.Config{Workspaces: []config.Workspace{
+		{Name: "w", Groups: []config.Group{
+			{Name: "spring-cloud", Repositories: []config.Repository{{Name: "a"}}},
+			{Name: "spring-cloud/deployers", Repositories: []config.Repository{{Name: "b"}}},
+			{Name: "spring-cloud-extra", Repositories: []config.Repository{{Name: "c"}}},
+		}},
+	}}
+
+	cmd := newSelectionCommand()
+	cmd.Flags().Set(util.HandyCiFlagGroup, "spring-cloud")
+	if got := len(Selection(cmd)); got != 2 {
+		t.Fatalf("expected group and sub-group selected, got %d", got)
+	}
+
+	cmd.Flags().Set(util.HandyCiFlagGroup, "spring-cloud/deployers")
+	if got := Selection(cmd); len(got) != 1 || got[0].Repository.Name != "b" {
+		t.Fatalf("expected only sub-group selected, got %v", got)
+	}
+}
+
+func TestOrderByDependencies(t *testing.T) {
+	workspace := config.Workspace{Name: "w"}
+	targets := []Target{
+		{Workspace: workspace, Repository: config.Repository{Name: "app", DependsOn: []string{"lib", "outside"}}},
+		{Workspace: workspace, Repository: config.Repository{Name: "lib", DependsOn: []string{"core"}}},
+		{Workspace: workspace, Repository: config.Repository{Name: "core"}},
+	}
+
+	ordered, err := OrderByDependencies(targets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, target := range ordered {
+		names = append(names, target.Repository.Name)
+	}
+	if strings.Join(names, " ") != "core lib app" {
+		t.Fatalf("unexpected order: %v", names)
+	}
+
+	targets[2].Repository.DependsOn = []string{"app"}
+	if _, err := OrderByDependencies(targets); err == nil {
+		t.Fatalf("expected error for dependency cycle")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package execution
+
+import "os"
+
+// terminalWidth returns the number of columns of terminal file, 0 when unknown.
+func terminalWidth(file *os.File) int {
+	return 0
+}
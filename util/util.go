@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/logrusorgru/aurora"
@@ -22,28 +23,78 @@ const HandyCiFlagFromShorthand = "F"
 const HandyCiFlagSkip = "skip"
 const HandyCiFlagContinue = "continue"
 const HandyCiFlagContinueShorthand = "C"
+const HandyCiFlagFailAtEnd = "fail-at-end"
+const HandyCiFlagFailFast = "fail-fast"
+const HandyCiFlagIncludeArchived = "include-archived"
 const HandyCiExecFlagNonStrict = "non-strict"
 const HandyCiFlagConfig = "config"
 const HandyCiFlagDryRun = "dry-run"
+const HandyCiFlagTimeout = "timeout"
+const HandyCiFlagDeadline = "deadline"
+const HandyCiFlagRetry = "retry"
+const HandyCiFlagPrefix = "prefix"
+const HandyCiFlagKeepLogs = "keep-logs"
+const HandyCiFlagKeepHistory = "keep-history"
+const HandyCiFlagSince = "since"
+const HandyCiFlagLimit = "limit"
+const HandyCiFlagRun = "run"
+const HandyCiFlagFailed = "failed"
+const HandyCiFlagFollow = "follow"
+const HandyCiFlagList = "list"
+const HandyCiFlagQuiet = "quiet"
+const HandyCiFlagShowIf = "show-if"
+const HandyCiFlagNonInteractive = "non-interactive"
+const HandyCiFlagProgress = "progress"
+const HandyCiFlagOutput = "output"
 const HandyCiFlagHelp = "help"
+const HandyCiFlagResolved = "resolved"
+const HandyCiFlagPath = "path"
+const HandyCiFlagNameIgnoredInPath = "name-ignored-in-path"
+const HandyCiFlagRemote = "remote"
+const HandyCiFlagFromURL = "from-url"
+const HandyCiFlagNoClone = "no-clone"
+const HandyCiFlagFormat = "format"
+const HandyCiFlagName = "name"
+const HandyCiFlagWrite = "write"
 
 func Printf(format string, a ...interface{}) (n int, err error) {
+	return Fprintf(os.Stdout, format, a...)
+}
+
+func Println(a ...interface{}) (n int, err error) {
+	output := fmt.Sprint(a...)
+
+	if output != "" {
+		return fmt.Println(aurora.Green("[Handy CI]"), " ", output)
+	} else {
+		return fmt.Println()
+	}
+}
+
+// Fprintf is Printf writing to out, such as the buffered output of a repository.
+func Fprintf(out io.Writer, format string, a ...interface{}) (n int, err error) {
 	output := fmt.Sprintf(format, a...)
 
 	if output != "" {
-		return fmt.Print(aurora.Green("[Handy CI]"), " ", output)
+		return fmt.Fprint(out, aurora.Green("[Handy CI]"), " ", output)
 	} else {
-		return fmt.Print()
+		return fmt.Fprint(out)
 	}
 }
 
-func Println(a ...interface{}) (n int, err error) {
+// Eprintf is Printf writing to standard error, for messages that shouldn't mix with command output.
+func Eprintf(format string, a ...interface{}) (n int, err error) {
+	return Fprintf(os.Stderr, format, a...)
+}
+
+// Eprintln is Println writing to standard error, for messages that shouldn't mix with command output.
+func Eprintln(a ...interface{}) (n int, err error) {
 	output := fmt.Sprint(a...)
 
 	if output != "" {
-		return fmt.Println(aurora.Green("[Handy CI]"), " ", output)
+		return fmt.Fprintln(os.Stderr, aurora.Green("[Handy CI]"), " ", output)
 	} else {
-		return fmt.Println()
+		return fmt.Fprintln(os.Stderr)
 	}
 }
 
@@ -57,6 +108,18 @@ func ContainArgs(args []string, arg string) bool {
 	return false
 }
 
+// IsTerminal tells whether file is a terminal, a character device other than the null device.
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	null, err := os.Stat(os.DevNull)
+
+	return err != nil || !os.SameFile(info, null)
+}
+
 func Home() string {
 	home, err := homedir.Dir()
 	if err != nil {